@@ -1,6 +1,9 @@
 package main
 
 import (
+	// embedded time zone database, the runtime image has no zoneinfo
+	_ "time/tzdata"
+
 	"github.com/soltanoff/go_github_release_monitor_bot/internal"
 )
 
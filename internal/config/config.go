@@ -8,13 +8,39 @@ import (
 	"github.com/caarlos0/env/v11"
 )
 
-var GithubPattern = regexp.MustCompile(`^https://github\.com/([\w-]+/[\w-]+)$`)
+var (
+	GithubPattern         = regexp.MustCompile(`^https://github\.com/([\w-]+/[\w-]+)$`)
+	GithubOwnerPattern    = regexp.MustCompile(`^https://github\.com/([\w-]+)/?$`)
+	GithubUsernamePattern = regexp.MustCompile(`^[A-Za-z\d][A-Za-z\d-]{0,38}$`)
+)
 
 type Config struct {
 	TelegramAPIKey     string        `env:"TELEGRAM_API_KEY,required"`
 	SurveyPeriod       time.Duration `env:"SURVEY_PERIOD" envDefault:"3600s"`
+	SurveyMinPeriod    time.Duration `env:"SURVEY_MIN_PERIOD" envDefault:"600s"`
+	SurveyMaxPeriod    time.Duration `env:"SURVEY_MAX_PERIOD" envDefault:"86400s"`
 	FetchingStepPeriod time.Duration `env:"FETCHING_STEP_PERIOD" envDefault:"60s"`
+	FetchingBurst      int           `env:"FETCHING_BURST" envDefault:"1"`
+	FetchingWorkers    int           `env:"FETCHING_WORKERS" envDefault:"4"`
+	FetchingTimeout    time.Duration `env:"FETCHING_TIMEOUT" envDefault:"30s"`
+	ManualStepPeriod   time.Duration `env:"MANUAL_FETCHING_STEP_PERIOD" envDefault:"30s"`
+	ManualBurst        int           `env:"MANUAL_FETCHING_BURST" envDefault:"10"`
+	GithubToken        string        `env:"GITHUB_TOKEN"`
+	CheckCooldown      time.Duration `env:"MANUAL_CHECK_COOLDOWN" envDefault:"300s"`
+	InlineCacheTTL     time.Duration `env:"INLINE_CACHE_TTL" envDefault:"600s"`
+	ConversationTTL    time.Duration `env:"CONVERSATION_TIMEOUT" envDefault:"300s"`
 	DBName             string        `env:"DB_NAME" envDefault:"db.sqlite3"`
+	OrphanRetention    time.Duration `env:"ORPHAN_RETENTION_PERIOD" envDefault:"168h"`
+	InstanceID         string        `env:"INSTANCE_ID"`
+	AdminIDs           []int64       `env:"ADMIN_IDS" envSeparator:","`
+	LeaderLeaseTTL     time.Duration `env:"LEADER_LEASE_TTL" envDefault:"30s"`
+	WebhookURL         string        `env:"WEBHOOK_URL"`
+	WebhookListen      string        `env:"WEBHOOK_LISTEN" envDefault:":8080"`
+	DispatchPeriod     time.Duration `env:"NOTIFICATION_DISPATCH_PERIOD" envDefault:"5s"`
+	DispatchRetryDelay time.Duration `env:"NOTIFICATION_RETRY_DELAY" envDefault:"30s"`
+	DispatchAttempts   int           `env:"NOTIFICATION_MAX_ATTEMPTS" envDefault:"5"`
+	DispatchRetention  time.Duration `env:"NOTIFICATION_RETENTION_PERIOD" envDefault:"720h"`
+	Template           string        `env:"NOTIFICATION_TEMPLATE"`
 }
 
 func LoadConfigFromEnv() (*Config, error) {
@@ -0,0 +1,183 @@
+package controller
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/controller/handlers"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/controller/logs"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/entities"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/i18n"
+)
+
+const (
+	callbackDataDelimiter string = ":"
+	// callbackSignatureSize keeps the callback data within the Telegram limit of 64 bytes
+	callbackSignatureSize int = 8
+)
+
+// ReleaseKeyboard returns the inline keyboard attached to release notifications.
+func (bc *BotController) ReleaseKeyboard(
+	language string,
+	chatID int64,
+	repositoryID uint,
+	releaseURL string,
+) models.ReplyMarkup {
+	var notesRow []models.InlineKeyboardButton
+
+	if releaseURL != "" {
+		notesRow = append(notesRow, models.InlineKeyboardButton{Text: i18n.T(language, "Release notes"), URL: releaseURL})
+	}
+
+	notesRow = append(notesRow, models.InlineKeyboardButton{
+		Text:         i18n.T(language, "Show history"),
+		CallbackData: bc.signCallbackData(handlers.CallbackHistory, chatID, repositoryID),
+	})
+
+	return &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{
+		notesRow,
+		{
+			{
+				Text:         i18n.T(language, "Mute for 7 days"),
+				CallbackData: bc.signCallbackData(handlers.CallbackMute, chatID, repositoryID),
+			},
+			{
+				Text:         i18n.T(language, "Unsubscribe"),
+				CallbackData: bc.signCallbackData(handlers.CallbackUnsubscribe, chatID, repositoryID),
+			},
+		},
+	}}
+}
+
+// signCallbackData builds "action:id:signature", the signature binds the data to the chat,
+// so a crafted callback query can't act on behalf of another chat or on another repository.
+func (bc *BotController) signCallbackData(action string, chatID int64, id uint) string {
+	return bc.signCallbackPayload(fmt.Sprintf("%s%s%d", action, callbackDataDelimiter, id), chatID)
+}
+
+func (bc *BotController) signCallbackPayload(payload string, chatID int64) string {
+	return payload + callbackDataDelimiter + bc.callbackSignature(payload, chatID)
+}
+
+func (bc *BotController) verifyCallbackData(data string, chatID int64) bool {
+	payload, signature, found := cutLast(data, callbackDataDelimiter)
+	if !found {
+		return false
+	}
+
+	return hmac.Equal([]byte(signature), []byte(bc.callbackSignature(payload, chatID)))
+}
+
+func (bc *BotController) callbackSignature(payload string, chatID int64) string {
+	mac := hmac.New(sha256.New, bc.callbackSecret)
+	mac.Write(fmt.Appendf(nil, "%d%s%s", chatID, callbackDataDelimiter, payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:callbackSignatureSize])
+}
+
+func cutLast(text string, separator string) (string, string, bool) {
+	index := strings.LastIndex(text, separator)
+	if index < 0 {
+		return text, "", false
+	}
+
+	return text[:index], text[index+len(separator):], true
+}
+
+// callbackChat returns the chat and the message the pressed button belongs to.
+func callbackChat(query *models.CallbackQuery) (models.Chat, int, bool) {
+	switch {
+	case query.Message.Message != nil:
+		return query.Message.Message.Chat, query.Message.Message.ID, true
+	case query.Message.InaccessibleMessage != nil:
+		return query.Message.InaccessibleMessage.Chat, query.Message.InaccessibleMessage.MessageID, true
+	default:
+		return models.Chat{}, 0, false
+	}
+}
+
+func (bc *BotController) callbackHandlerWrapper(handler HandlerFunc) bot.HandlerFunc {
+	return func(ctx context.Context, _ *bot.Bot, update *models.Update) {
+		logs.LogBotIncomingMessage(update)
+
+		// the button stays in the loading state until the query is answered
+		defer bc.answerCallbackQuery(ctx, update)
+
+		chatID, messageID, user, ok := bc.callbackUser(ctx, update)
+		if !ok {
+			return
+		}
+
+		answer := i18n.T(user.Language, handler(ctx, update, &user))
+		disableWebPagePreview := true
+
+		// the answer goes to the forum topic of the pressed button
+		var messageThreadID int
+		if message := update.CallbackQuery.Message.Message; message != nil {
+			messageThreadID = handlers.TopicID(message)
+		}
+
+		_, err := bc.sendMessage(ctx, &bot.SendMessageParams{
+			ChatID:             chatID,
+			MessageThreadID:    messageThreadID,
+			Text:               answer,
+			ParseMode:          models.ParseModeHTML,
+			ReplyParameters:    &models.ReplyParameters{MessageID: messageID, AllowSendingWithoutReply: true},
+			LinkPreviewOptions: &models.LinkPreviewOptions{IsDisabled: &disableWebPagePreview},
+		})
+		if err != nil {
+			logs.LogBotErrorMessage(update, err)
+
+			return
+		}
+
+		logs.LogBotOutgoingMessage(update, answer)
+	}
+}
+
+// callbackUser verifies the callback data and returns the chat, the message and the user who pressed the button.
+func (bc *BotController) callbackUser(
+	ctx context.Context,
+	update *models.Update,
+) (int64, int, entities.User, bool) {
+	query := update.CallbackQuery
+
+	chat, messageID, ok := callbackChat(query)
+	if !ok || !bc.verifyCallbackData(query.Data, chat.ID) {
+		slog.Warn("[BOT] Callback query rejected", "senderID", query.From.ID, "data", query.Data)
+
+		return 0, 0, entities.User{}, false
+	}
+
+	user, err := bc.getUser(ctx, &chat, &query.From)
+	if err != nil {
+		logs.LogBotErrorMessage(update, err)
+
+		return 0, 0, entities.User{}, false
+	}
+
+	return chat.ID, messageID, user, true
+}
+
+func (bc *BotController) answerCallbackQuery(ctx context.Context, update *models.Update) {
+	_, err := bc.bot.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: update.CallbackQuery.ID})
+	if err != nil {
+		logs.LogBotErrorMessage(update, err)
+	}
+}
+
+func (bc *BotController) registerCallbackHandler(action string, handler HandlerFunc) {
+	bc.bot.RegisterHandler(
+		bot.HandlerTypeCallbackQueryData,
+		action+callbackDataDelimiter,
+		bot.MatchTypePrefix,
+		bc.callbackHandlerWrapper(handler),
+	)
+}
@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/controller/handlers"
+)
+
+// telegramCallbackDataLimit is the Telegram limit of the callback data in bytes.
+const telegramCallbackDataLimit int = 64
+
+func TestSignedCallbackDataFitsTelegramLimit(t *testing.T) {
+	t.Parallel()
+
+	bc := &BotController{callbackSecret: callbackSecret("token")}
+	// the longest query the list accepts on the latest page anyone reaches
+	query, ok := handlers.ParseSubscriptionsQuery("/my_subscriptions --sort=release " + strings.Repeat("a", 32))
+	if !ok {
+		t.Fatal("the longest filter is rejected")
+	}
+
+	query.Page = 9999
+
+	data := []string{
+		bc.signCallbackPayload(query.Payload(), math.MinInt64),
+		bc.signCallbackData(handlers.CallbackUnsubscribe, math.MinInt64, math.MaxUint),
+		bc.signCallbackData(handlers.CallbackHistory, math.MinInt64, math.MaxUint),
+	}
+
+	for _, callbackData := range data {
+		if len(callbackData) > telegramCallbackDataLimit {
+			t.Errorf("%q is %d bytes, the limit is %d", callbackData, len(callbackData), telegramCallbackDataLimit)
+		}
+
+		if !bc.verifyCallbackData(callbackData, math.MinInt64) {
+			t.Errorf("%q isn't verified", callbackData)
+		}
+	}
+}
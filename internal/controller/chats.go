@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/controller/handlers"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/controller/logs"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/entities"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/i18n"
+)
+
+const chatAdminsOnlyMessage string = "Only chat admins can change subscriptions and settings of this chat"
+
+// getUser returns the subscriber of the chat, the language of a new chat is taken from the sender Telegram client.
+func (bc *BotController) getUser(ctx context.Context, chat *models.Chat, sender *models.User) (entities.User, error) {
+	user, err := bc.repository.GetOrCreateUser(ctx, chat.ID)
+	if err != nil {
+		return user, err
+	}
+
+	var columns []string
+
+	if user.ChatType != string(chat.Type) {
+		user.ChatType = string(chat.Type)
+		columns = append(columns, "chat_type")
+	}
+
+	if user.Language == "" {
+		// channel posts have no sender
+		var languageCode string
+		if sender != nil {
+			languageCode = sender.LanguageCode
+		}
+
+		user.Language = i18n.Detect(languageCode)
+		columns = append(columns, "language")
+	}
+
+	if len(columns) == 0 {
+		return user, nil
+	}
+
+	return user, bc.repository.UpdateUserSettings(ctx, &user, columns...)
+}
+
+// adminOnly restricts the handler to chat admins, everyone manages their own private chat.
+func (bc *BotController) adminOnly(handler HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, update *models.Update, user *entities.User) string {
+		allowed, err := bc.canManageChat(ctx, update)
+		if err != nil {
+			logs.LogBotErrorMessage(update, err)
+
+			return handlers.ErrorMessage
+		}
+
+		if !allowed {
+			return chatAdminsOnlyMessage
+		}
+
+		return handler(ctx, update, user)
+	}
+}
+
+// adminOnlyChanges shows the setting to everyone, only chat admins change it or are asked for a new value.
+func (bc *BotController) adminOnlyChanges(pattern string, handler HandlerFunc) HandlerFunc {
+	askNewValue := bc.askNewValue(pattern, handler)
+	change := bc.adminOnly(askNewValue)
+
+	return func(ctx context.Context, update *models.Update, user *entities.User) string {
+		if len(strings.Fields(update.Message.Text)) > 1 {
+			return change(ctx, update, user)
+		}
+
+		allowed, err := bc.canManageChat(ctx, update)
+		if err != nil {
+			logs.LogBotErrorMessage(update, err)
+		}
+
+		if !allowed {
+			return handler(ctx, update, user)
+		}
+
+		return askNewValue(ctx, update, user)
+	}
+}
+
+func (bc *BotController) canManageChat(ctx context.Context, update *models.Update) (bool, error) {
+	var (
+		chat       models.Chat
+		sender     *models.User
+		senderChat *models.Chat
+	)
+
+	switch {
+	case update.Message != nil:
+		chat, sender, senderChat = update.Message.Chat, update.Message.From, update.Message.SenderChat
+	case update.CallbackQuery != nil:
+		chat, _, _ = callbackChat(update.CallbackQuery)
+		sender = &update.CallbackQuery.From
+	}
+
+	switch {
+	case chat.Type == models.ChatTypePrivate:
+		return true, nil
+	case senderChat != nil && senderChat.ID == chat.ID:
+		// channel posts and anonymous group admins are sent on behalf of the chat
+		return true, nil
+	case sender == nil:
+		return false, nil
+	}
+
+	member, err := bc.bot.GetChatMember(ctx, &bot.GetChatMemberParams{ChatID: chat.ID, UserID: sender.ID})
+	if err != nil {
+		return false, fmt.Errorf("[BOT] get chat member failed: %w", err)
+	}
+
+	return member.Type == models.ChatMemberTypeOwner || member.Type == models.ChatMemberTypeAdministrator, nil
+}
+
+// defaultUpdateHandler receives every unmatched update.
+func (bc *BotController) defaultUpdateHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	switch {
+	case update.ChannelPost != nil:
+		// channel posts are handled as messages, so channels are managed with the same commands
+		message := *update
+		message.Message = update.ChannelPost
+		message.ChannelPost = nil
+		b.ProcessUpdate(ctx, &message)
+	case bc.continueConversation(ctx, b, update):
+		// the message was the answer to the bot question
+		return
+	case update.Message != nil && update.Message.MigrateToChatID != 0:
+		err := bc.repository.MigrateUserChat(ctx, update.Message.Chat.ID, update.Message.MigrateToChatID)
+		if err != nil {
+			logs.LogBotErrorMessage(update, err)
+		}
+	case update.Message != nil && update.Message.Chat.Type != models.ChatTypePrivate:
+		// group conversations aren't answered, only commands are
+		return
+	default:
+		bc.handlerWrapper(withoutReplyMarkup(bc.defaultHandler), true)(ctx, b, update)
+	}
+}
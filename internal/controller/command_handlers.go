@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"github.com/go-telegram/bot"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/controller/handlers"
+)
+
+func (bc *BotController) registerSubscriptionHandlers() {
+	bc.registerReplyHandler(
+		"/my_subscriptions",
+		"[--sort=name|release] [filter] view your subscriptions page by page",
+		true,
+		bc.mySubscriptionsHandler,
+	)
+	bc.bot.RegisterHandler(
+		bot.HandlerTypeCallbackQueryData,
+		handlers.CallbackSubscriptionsPage+callbackDataDelimiter,
+		bot.MatchTypePrefix,
+		bc.subscriptionsPageCallback,
+	)
+	bc.registerHandler(
+		"/subscribe",
+		"[github repository or owner urls] [--include=glob] [--exclude=glob] [--skip-archived] [--skip-forks] "+
+			"subscribe to the new GitHub repository or to all repositories of the owner",
+		true,
+		bc.adminOnly(bc.askArguments(
+			"/subscribe",
+			"Send me GitHub repository or owner links or /cancel",
+			bc.subscriptionHandler.SubscribeHandler,
+		)),
+	)
+	bc.registerHandler(
+		"/unsubscribe",
+		"[github repository or owner urls] unsubscribe from the GitHub repository or owner",
+		true,
+		bc.adminOnly(bc.askArguments(
+			"/unsubscribe",
+			"Send me GitHub repository or owner links to unsubscribe from or /cancel",
+			bc.subscriptionHandler.UnsubscribeHandler,
+		)),
+	)
+	bc.registerHandler(
+		"/remove_all_subscriptions",
+		"[yes] remove all exists subscriptions after the confirmation",
+		true,
+		bc.adminOnly(bc.askArguments(
+			"/remove_all_subscriptions",
+			"Send yes to remove all subscriptions or /cancel",
+			bc.subscriptionHandler.RemoveAllSubscriptionsHandler,
+		)),
+	)
+	bc.registerHandler(
+		"/import_stars",
+		"[github username] [sync|stop] subscribe to all repositories starred by the GitHub user",
+		true,
+		bc.adminOnly(bc.askArguments(
+			"/import_stars",
+			"Send me a GitHub username or /cancel",
+			bc.starsHandler.ImportStarsHandler,
+		)),
+	)
+	bc.registerDocumentHandler(
+		"[go.mod, package.json, requirements.txt, Cargo.toml or pom.xml file]",
+		"subscribe to all dependencies from the uploaded manifest",
+		true,
+		bc.manifestHandler.UploadManifestHandler,
+	)
+}
+
+func (bc *BotController) registerSettingsHandlers() {
+	bc.registerHandler(
+		"/delivery",
+		"[instant|daily|weekly] [hour] [weekday] view or change the notification delivery mode",
+		true,
+		bc.adminOnlyChanges("/delivery", bc.settingsHandler.DeliveryHandler),
+	)
+	bc.registerHandler(
+		"/timezone",
+		"[IANA time zone] view or change your time zone",
+		true,
+		bc.adminOnlyChanges("/timezone", bc.settingsHandler.TimezoneHandler),
+	)
+	bc.registerHandler(
+		"/quiet",
+		"[HH:MM-HH:MM|off] [hold|silent] view or change quiet hours",
+		true,
+		bc.adminOnlyChanges("/quiet", bc.settingsHandler.QuietHoursHandler),
+	)
+	bc.registerHandler(
+		"/template",
+		"[preset|reset|custom text/template] view or change the notification template",
+		true,
+		bc.adminOnlyChanges("/template", bc.settingsHandler.TemplateHandler),
+	)
+	bc.registerHandler(
+		"/language",
+		"[en|ru] view or change the bot language",
+		true,
+		bc.adminOnlyChanges("/language", bc.settingsHandler.LanguageHandler),
+	)
+}
+
+func (bc *BotController) registerStatusHandlers() {
+	bc.registerHandler(
+		"/check",
+		"[github repository url] check the repository for a new release right away",
+		true,
+		bc.checkHandler.CheckHandler,
+	)
+	bc.registerHandler(
+		"/history",
+		"[github repository url] [count] view the last releases of the repository",
+		true,
+		bc.historyHandler.HistoryHandler,
+	)
+	bc.registerHandler(
+		"/status",
+		"view the health of your subscriptions",
+		true,
+		bc.statusHandler.StatusHandler,
+	)
+	bc.registerHandler(
+		"/health_report",
+		"view the most failing repositories (admins only)",
+		true,
+		bc.statusHandler.FailingReportHandler,
+	)
+}
+
+func (bc *BotController) registerReleaseActionHandlers() {
+	bc.registerCallbackHandler(handlers.CallbackHistory, bc.releaseActions.HistoryHandler)
+	bc.registerCallbackHandler(handlers.CallbackMute, bc.adminOnly(bc.releaseActions.MuteHandler))
+	bc.registerCallbackHandler(handlers.CallbackUnsubscribe, bc.adminOnly(bc.releaseActions.UnsubscribeHandler))
+}
@@ -2,22 +2,47 @@ package controller
 
 import (
 	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 	"github.com/soltanoff/go_github_release_monitor_bot/internal/config"
 	"github.com/soltanoff/go_github_release_monitor_bot/internal/controller/handlers"
 	"github.com/soltanoff/go_github_release_monitor_bot/internal/entities"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/importer"
 	"github.com/soltanoff/go_github_release_monitor_bot/internal/repo"
 )
 
+var (
+	ErrFileTooLarge      = errors.New("file is too large")
+	ErrUnsupportedChatID = errors.New("unsupported chat id")
+)
+
 type BotController struct {
 	bot                 *bot.Bot
 	repository          *repo.Repository
 	subscriptionHandler *handlers.SubscriptionsHandler
-	commandList         []string
+	starsHandler        *handlers.StarsHandler
+	manifestHandler     *handlers.ManifestHandler
+	settingsHandler     *handlers.SettingsHandler
+	statusHandler       *handlers.StatusHandler
+	checkHandler        *handlers.CheckHandler
+	historyHandler      *handlers.HistoryHandler
+	releaseActions      *handlers.ReleaseActionsHandler
+	inlineHandler       *handlers.InlineHandler
+	sendQueue           *sendQueue
+	conversations       *conversations
+	// callbackSecret signs the callback data of inline buttons
+	callbackSecret []byte
+	commandList    []commandDescription
+	webhookURL     string
+	webhookListen  string
+	webhookSecret  string
 }
 
 type HandlerFunc func(ctx context.Context, update *models.Update, user *entities.User) string
@@ -25,65 +50,87 @@ type HandlerFunc func(ctx context.Context, update *models.Update, user *entities
 func NewBotController(
 	cfg *config.Config,
 	repository *repo.Repository,
+	repositoryImporter *importer.Importer,
+	checker handlers.RepositoryChecker,
+	lookup handlers.ReleaseLookup,
 ) (*BotController, error) {
 	// bot.WithErrorsHandler(): логгировать ошибку на самом высоком уровне и/или использовать свой logger?
-	b, err := bot.New(cfg.TelegramAPIKey)
+	b, err := bot.New(cfg.TelegramAPIKey, bot.WithWebhookSecretToken(webhookSecret(cfg.TelegramAPIKey)))
 	if err != nil {
 		slog.Error("[BOT] Bot init error", "error", err.Error())
 
 		return nil, fmt.Errorf("[BOT] failed to connect Telegram API: %w", err)
 	}
 
-	subscriptionHandler := handlers.NewSubscriptionsHandler(repository)
+	subscriptionHandler := handlers.NewSubscriptionsHandler(repository, repositoryImporter, checker, cfg.FetchingTimeout)
+	starsHandler := handlers.NewStarsHandler(repository, repositoryImporter)
+	settingsHandler := handlers.NewSettingsHandler(repository)
 
-	bc := BotController{bot: b, repository: repository, subscriptionHandler: subscriptionHandler}
+	bc := BotController{
+		bot:                 b,
+		repository:          repository,
+		subscriptionHandler: subscriptionHandler,
+		starsHandler:        starsHandler,
+		settingsHandler:     settingsHandler,
+		statusHandler:       handlers.NewStatusHandler(repository, cfg.AdminIDs),
+		checkHandler:        handlers.NewCheckHandler(repository, checker, cfg.CheckCooldown),
+		historyHandler:      handlers.NewHistoryHandler(repository),
+		inlineHandler:       handlers.NewInlineHandler(lookup),
+		sendQueue:           newSendQueue(),
+		conversations:       newConversations(cfg.ConversationTTL),
+		callbackSecret:      callbackSecret(cfg.TelegramAPIKey),
+		webhookURL:          cfg.WebhookURL,
+		webhookListen:       cfg.WebhookListen,
+		webhookSecret:       webhookSecret(cfg.TelegramAPIKey),
+	}
+	bc.releaseActions = handlers.NewReleaseActionsHandler(repository, bc.historyHandler)
+	bc.manifestHandler = handlers.NewManifestHandler(repositoryImporter, &bc, &bc)
 	bc.registerDefaultMiddlewares()
 	bc.registerDefaultHandler()
-	bc.registerHandler(
-		"/my_subscriptions",
-		"view all subscriptions",
-		true,
-		subscriptionHandler.MySubscriptionsHandler,
-	)
-	bc.registerHandler(
-		"/subscribe",
-		"[github repository urls] subscribe to the new GitHub repository",
-		true,
-		subscriptionHandler.SubscribeHandler,
-	)
-	bc.registerHandler(
-		"/unsubscribe",
-		"[github repository urls] unsubscribe from the GitHub repository",
-		true,
-		subscriptionHandler.UnsubscribeHandler,
-	)
-	bc.registerHandler(
-		"/remove_all_subscriptions",
-		"remove all exists subscriptions",
-		true,
-		subscriptionHandler.RemoveAllSubscriptionsHandler,
-	)
+	bc.registerSubscriptionHandlers()
+	bc.registerSettingsHandlers()
+	bc.registerStatusHandlers()
+	bc.registerReleaseActionHandlers()
+	bc.registerInlineHandler()
 
 	return &bc, nil
 }
 
+// Start receives Telegram updates with the long poll, Telegram allows one per bot token.
 func (bc *BotController) Start(ctx context.Context) {
+	// the long poll is refused while a webhook is set, e.g. one left after switching from the webhook mode
+	if _, err := bc.bot.DeleteWebhook(ctx, &bot.DeleteWebhookParams{}); err != nil {
+		slog.Error("[BOT] Delete webhook failed", "error", err)
+	}
+
 	slog.Info("[BOT] Starting bot...")
 	bc.bot.Start(ctx)
 	slog.Info("[BOT] Close bot controller...")
 }
 
+type MessageOptions struct {
+	DisableWebPagePreview bool
+	// DisableNotification delivers the message silently
+	DisableNotification bool
+	ReplyMarkup         models.ReplyMarkup
+	// MessageThreadID is the forum topic of the message, zero is the chat itself
+	MessageThreadID int
+}
+
 func (bc *BotController) SendMessage(
 	ctx context.Context,
 	userExternalID int64,
 	answer string,
-	disableWebPagePreview bool,
+	options MessageOptions,
 ) error {
-	_, err := bc.bot.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID:             userExternalID,
-		Text:               answer,
-		ParseMode:          models.ParseModeHTML,
-		LinkPreviewOptions: &models.LinkPreviewOptions{IsDisabled: &disableWebPagePreview},
+	_, err := bc.sendMessage(ctx, &bot.SendMessageParams{
+		ChatID:              userExternalID,
+		MessageThreadID:     options.MessageThreadID,
+		Text:                answer,
+		ParseMode:           models.ParseModeHTML,
+		LinkPreviewOptions:  &models.LinkPreviewOptions{IsDisabled: &options.DisableWebPagePreview},
+		DisableNotification: options.DisableNotification,
+		ReplyMarkup:         options.ReplyMarkup,
 	})
 	if err != nil {
 		return fmt.Errorf("[BOT] send message failed: %w", err)
@@ -93,3 +140,78 @@ func (bc *BotController) SendMessage(
 
 	return nil
 }
+
+// callbackSecret derives the callback signing key from the bot token, which is a secret anyway.
+func callbackSecret(telegramAPIKey string) []byte {
+	secret := sha256.Sum256([]byte("callback:" + telegramAPIKey))
+
+	return secret[:]
+}
+
+// sendMessage sends every message through the send queue to respect Telegram limits.
+func (bc *BotController) sendMessage(ctx context.Context, params *bot.SendMessageParams) (*models.Message, error) {
+	chatID, ok := params.ChatID.(int64)
+	if !ok {
+		return nil, fmt.Errorf("[BOT] %w: %v", ErrUnsupportedChatID, params.ChatID)
+	}
+
+	var sent *models.Message
+
+	err := bc.sendQueue.send(ctx, chatID, func(ctx context.Context) error {
+		var err error
+
+		sent, err = bc.bot.SendMessage(ctx, params)
+
+		return err
+	})
+
+	return sent, err
+}
+
+// ReplyMessage answers the message outside of its handler, e.g. when a background import is done.
+func (bc *BotController) ReplyMessage(ctx context.Context, message *models.Message, answer string) error {
+	disableWebPagePreview := true
+
+	_, err := bc.sendMessage(ctx, &bot.SendMessageParams{
+		ChatID:             message.Chat.ID,
+		MessageThreadID:    handlers.TopicID(message),
+		Text:               answer,
+		ParseMode:          models.ParseModeHTML,
+		ReplyParameters:    &models.ReplyParameters{MessageID: message.ID},
+		LinkPreviewOptions: &models.LinkPreviewOptions{IsDisabled: &disableWebPagePreview},
+	})
+	if err != nil {
+		return fmt.Errorf("[BOT] reply message failed: %w", err)
+	}
+
+	return nil
+}
+
+func (bc *BotController) DownloadFile(ctx context.Context, fileID string, maxSize int64) ([]byte, error) {
+	file, err := bc.bot.GetFile(ctx, &bot.GetFileParams{FileID: fileID})
+	if err != nil {
+		return nil, fmt.Errorf("[BOT] get file failed: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, bc.bot.FileDownloadLink(file), http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("[BOT] download file failed: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("[BOT] download file failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("[BOT] download file failed: %w", err)
+	}
+
+	if int64(len(content)) > maxSize {
+		return nil, fmt.Errorf("[BOT] %w: %s", ErrFileTooLarge, fileID)
+	}
+
+	return content, nil
+}
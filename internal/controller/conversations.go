@@ -0,0 +1,189 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/entities"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/i18n"
+)
+
+const (
+	conversationCancelled      string = "Cancelled"
+	conversationNothingPending string = "Nothing to cancel"
+	conversationExpired        string = "The %s command timed out, please send it again"
+	conversationNewValuePrompt string = "Send a new value or /cancel"
+)
+
+// conversation is a command waiting for its arguments in the next message of the same sender.
+type conversation struct {
+	pattern string
+	// promptMessageID is the bot question, answers in groups have to reply to it
+	promptMessageID int
+	expiresAt       time.Time
+}
+
+// conversationKey is the sender in the chat, group members have their own conversations.
+type conversationKey struct {
+	chatID   int64
+	senderID int64
+}
+
+type conversations struct {
+	mu      sync.Mutex
+	timeout time.Duration
+	pending map[conversationKey]conversation
+}
+
+func newConversations(timeout time.Duration) *conversations {
+	return &conversations{timeout: timeout, pending: make(map[conversationKey]conversation)}
+}
+
+func (c *conversations) start(key conversationKey, pattern string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+
+	// abandoned conversations are forgotten here, so the map doesn't grow
+	for pendingKey, pending := range c.pending {
+		if !now.Before(pending.expiresAt) {
+			delete(c.pending, pendingKey)
+		}
+	}
+
+	c.pending[key] = conversation{pattern: pattern, expiresAt: now.Add(c.timeout)}
+}
+
+// setPrompt remembers the question of the conversation the sender was just asked.
+func (c *conversations) setPrompt(key conversationKey, messageID int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if pending, ok := c.pending[key]; ok && pending.promptMessageID == 0 {
+		pending.promptMessageID = messageID
+		c.pending[key] = pending
+	}
+}
+
+// takeAnswer removes the conversation the message answers, in groups only a reply to the question is
+// an answer, so other talk of the sender doesn't run the command.
+func (c *conversations) takeAnswer(message *models.Message) (conversation, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := newConversationKey(message)
+
+	pending, ok := c.pending[key]
+	if !ok {
+		return conversation{}, false
+	}
+
+	isReply := message.ReplyToMessage != nil && message.ReplyToMessage.ID == pending.promptMessageID
+	if message.Chat.Type != models.ChatTypePrivate && !isReply {
+		return conversation{}, false
+	}
+
+	delete(c.pending, key)
+
+	return pending, true
+}
+
+// take removes the conversation of the sender, expired ones are returned as well.
+func (c *conversations) take(key conversationKey) (conversation, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pending, ok := c.pending[key]
+	delete(c.pending, key)
+
+	return pending, ok
+}
+
+func newConversationKey(message *models.Message) conversationKey {
+	key := conversationKey{chatID: message.Chat.ID}
+
+	// channel posts have no sender, the channel has one conversation
+	if message.From != nil {
+		key.senderID = message.From.ID
+	}
+
+	return key
+}
+
+// askArguments asks for the missing command arguments, the next message of the sender is handled as them.
+func (bc *BotController) askArguments(pattern string, prompt string, handler HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, update *models.Update, user *entities.User) string {
+		if len(strings.Fields(update.Message.Text)) > 1 {
+			return handler(ctx, update, user)
+		}
+
+		bc.conversations.start(newConversationKey(update.Message), pattern)
+
+		return prompt
+	}
+}
+
+// askNewValue shows the current setting and takes the next message of the sender as its new value.
+func (bc *BotController) askNewValue(pattern string, handler HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, update *models.Update, user *entities.User) string {
+		answer := handler(ctx, update, user)
+		if len(strings.Fields(update.Message.Text)) > 1 {
+			return answer
+		}
+
+		bc.conversations.start(newConversationKey(update.Message), pattern)
+
+		return i18n.T(user.Language, answer) + "\n\n" + i18n.T(user.Language, conversationNewValuePrompt)
+	}
+}
+
+// dropConversation forgets the question when the sender moves on to another command.
+func (bc *BotController) dropConversation(handler ReplyHandlerFunc) ReplyHandlerFunc {
+	return func(ctx context.Context, update *models.Update, user *entities.User) (string, models.ReplyMarkup) {
+		bc.conversations.take(newConversationKey(update.Message))
+
+		return handler(ctx, update, user)
+	}
+}
+
+func (bc *BotController) cancelHandler(_ context.Context, update *models.Update, _ *entities.User) string {
+	if _, ok := bc.conversations.take(newConversationKey(update.Message)); !ok {
+		return conversationNothingPending
+	}
+
+	return conversationCancelled
+}
+
+// continueConversation handles the message as the arguments of the command the sender was asked about.
+func (bc *BotController) continueConversation(ctx context.Context, b *bot.Bot, update *models.Update) bool {
+	message := update.Message
+	if message == nil || message.Text == "" {
+		return false
+	}
+
+	pending, ok := bc.conversations.takeAnswer(message)
+	if !ok {
+		return false
+	}
+
+	if !time.Now().Before(pending.expiresAt) {
+		bc.handlerWrapper(withoutReplyMarkup(func(_ context.Context, _ *models.Update, user *entities.User) string {
+			return i18n.Sprintf(user.Language, conversationExpired, pending.pattern)
+		}), true)(ctx, b, update)
+
+		return true
+	}
+
+	answer := *update
+	answerMessage := *message
+	answerMessage.Text = pending.pattern + " " + message.Text
+	answer.Message = &answerMessage
+	b.ProcessUpdate(ctx, &answer)
+
+	return true
+}
@@ -2,7 +2,6 @@ package controller
 
 import (
 	"context"
-	"strings"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
@@ -10,7 +9,7 @@ import (
 )
 
 func (bc *BotController) registerDefaultHandler() {
-	bot.WithDefaultHandler(bc.handlerWrapper(bc.defaultHandler, true))(bc.bot)
+	bot.WithDefaultHandler(bc.defaultUpdateHandler)(bc.bot)
 	bc.registerHandler(
 		"/start",
 		"base command for user registration",
@@ -23,12 +22,20 @@ func (bc *BotController) registerDefaultHandler() {
 		false,
 		bc.welcomeHandler,
 	)
+	// registered apart from other commands, which drop the pending conversation before it's checked
+	bc.bot.RegisterHandler(
+		bot.HandlerTypeMessageText,
+		"/cancel",
+		bot.MatchTypePrefix,
+		bc.handlerWrapper(withoutReplyMarkup(bc.cancelHandler), true),
+	)
+	bc.addCommandDescription("/cancel", "cancel the command waiting for your answer")
 }
 
 func (bc *BotController) defaultHandler(_ context.Context, _ *models.Update, _ *entities.User) string {
 	return "Say /help"
 }
 
-func (bc *BotController) welcomeHandler(_ context.Context, _ *models.Update, _ *entities.User) string {
-	return strings.Join(bc.commandList, "\n")
+func (bc *BotController) welcomeHandler(_ context.Context, _ *models.Update, user *entities.User) string {
+	return bc.formatCommandList(user.Language)
 }
@@ -6,28 +6,48 @@ import (
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/controller/handlers"
 	"github.com/soltanoff/go_github_release_monitor_bot/internal/controller/logs"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/entities"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/i18n"
 )
 
-func (bc *BotController) handlerWrapper(handler HandlerFunc, disableWebPagePreview bool) bot.HandlerFunc {
+// ReplyHandlerFunc is a handler whose answer may have an inline keyboard.
+type ReplyHandlerFunc func(ctx context.Context, update *models.Update, user *entities.User) (string, models.ReplyMarkup)
+
+func withoutReplyMarkup(handler HandlerFunc) ReplyHandlerFunc {
+	return func(ctx context.Context, update *models.Update, user *entities.User) (string, models.ReplyMarkup) {
+		return handler(ctx, update, user), nil
+	}
+}
+
+func (bc *BotController) handlerWrapper(handler ReplyHandlerFunc, disableWebPagePreview bool) bot.HandlerFunc {
 	return func(ctx context.Context, _ *bot.Bot, update *models.Update) {
+		if update.Message == nil {
+			return
+		}
+
 		logs.LogBotIncomingMessage(update)
 
-		user, err := bc.repository.GetOrCreateUser(ctx, update.Message.From.ID)
+		user, err := bc.getUser(ctx, &update.Message.Chat, update.Message.From)
 		if err != nil {
 			logs.LogBotErrorMessage(update, err)
 
 			return
 		}
 
-		answer := handler(ctx, update, &user)
+		// constant answers are translated here, formatted ones are translated by handlers
+		answer, replyMarkup := handler(ctx, update, &user)
+		answer = i18n.T(user.Language, answer)
 
-		_, err = bc.bot.SendMessage(ctx, &bot.SendMessageParams{
+		sent, err := bc.sendMessage(ctx, &bot.SendMessageParams{
 			ChatID:             update.Message.Chat.ID,
+			MessageThreadID:    handlers.TopicID(update.Message),
 			Text:               answer,
 			ParseMode:          models.ParseModeHTML,
 			ReplyParameters:    &models.ReplyParameters{MessageID: update.Message.ID},
 			LinkPreviewOptions: &models.LinkPreviewOptions{IsDisabled: &disableWebPagePreview},
+			ReplyMarkup:        replyMarkup,
 		})
 		if err != nil {
 			logs.LogBotErrorMessage(update, err)
@@ -35,6 +55,8 @@ func (bc *BotController) handlerWrapper(handler HandlerFunc, disableWebPagePrevi
 			return
 		}
 
+		// if the answer is a question, group members answer it by replying to this message
+		bc.conversations.setPrompt(newConversationKey(update.Message), sent.ID)
 		logs.LogBotOutgoingMessage(update, answer)
 	}
 }
@@ -44,18 +66,64 @@ func (bc *BotController) registerHandler(
 	description string,
 	disableWebPagePreview bool,
 	handler HandlerFunc,
+) {
+	bc.registerReplyHandler(pattern, description, disableWebPagePreview, withoutReplyMarkup(handler))
+}
+
+func (bc *BotController) registerReplyHandler(
+	pattern string,
+	description string,
+	disableWebPagePreview bool,
+	handler ReplyHandlerFunc,
 ) {
 	bc.bot.RegisterHandler(
 		bot.HandlerTypeMessageText,
 		pattern,
 		bot.MatchTypePrefix,
-		bc.handlerWrapper(handler, disableWebPagePreview),
+		bc.handlerWrapper(bc.dropConversation(handler), disableWebPagePreview),
+	)
+	bc.addCommandDescription(pattern, description)
+}
+
+func (bc *BotController) registerDocumentHandler(
+	pattern string,
+	description string,
+	disableWebPagePreview bool,
+	handler HandlerFunc,
+) {
+	bc.bot.RegisterHandlerMatchFunc(
+		func(update *models.Update) bool {
+			// groups and channels share files for other purposes, manifests are taken from private chats only
+			return update.Message != nil && update.Message.Document != nil &&
+				update.Message.Chat.Type == models.ChatTypePrivate
+		},
+		bc.handlerWrapper(withoutReplyMarkup(handler), disableWebPagePreview),
 	)
+	bc.addCommandDescription(pattern, description)
+}
 
+func (bc *BotController) addCommandDescription(pattern string, description string) {
+	bc.commandList = append(bc.commandList, commandDescription{pattern: pattern, description: description})
+}
+
+// commandDescription keeps the English description, it's translated for every user on demand.
+type commandDescription struct {
+	pattern     string
+	description string
+}
+
+func (bc *BotController) formatCommandList(language string) string {
 	var answer strings.Builder
 
-	answer.WriteString(pattern)
-	answer.WriteString(" - ")
-	answer.WriteString(description)
-	bc.commandList = append(bc.commandList, answer.String())
+	for index, command := range bc.commandList {
+		if index > 0 {
+			answer.WriteString("\n")
+		}
+
+		answer.WriteString(command.pattern)
+		answer.WriteString(" - ")
+		answer.WriteString(i18n.T(language, command.description))
+	}
+
+	return answer.String()
 }
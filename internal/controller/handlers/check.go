@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/config"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/controller/logs"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/entities"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/i18n"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/monitor/github"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/repo"
+	"gorm.io/gorm"
+)
+
+const (
+	checkUsageMessage         string = "Usage: /check [github repository url]"
+	checkNotSubscribedMessage string = "You are not subscribed to this repository, use /subscribe first"
+	checkCooldownMessage      string = "Please wait %s before the next check"
+	checkFailedMessage        string = "Cannot check the repository now, see /status for details"
+	checkCurrentMessage       string = "Current version of <a href=\"%s\">%s</a>: %s"
+	checkNewReleaseMessage    string = "New release detected, notifications are on the way!"
+)
+
+// RepositoryChecker fetches a repository from GitHub outside the survey schedule.
+type RepositoryChecker interface {
+	CheckRepository(ctx context.Context, repository *entities.Repository) error
+	GetRecentReleases(ctx context.Context, repository *entities.Repository, count int) ([]entities.Release, error)
+}
+
+type CheckHandler struct {
+	repository *repo.Repository
+	checker    RepositoryChecker
+	cooldown   time.Duration
+}
+
+func NewCheckHandler(repository *repo.Repository, checker RepositoryChecker, cooldown time.Duration) *CheckHandler {
+	return &CheckHandler{repository: repository, checker: checker, cooldown: cooldown}
+}
+
+// CheckHandler polls the subscribed repository right away,
+// the per-user cooldown protects the GitHub API quota shared by everybody.
+func (h *CheckHandler) CheckHandler(
+	ctx context.Context,
+	update *models.Update,
+	user *entities.User,
+) string {
+	args := strings.Fields(update.Message.Text)
+	if len(args) != 2 || !config.GithubPattern.MatchString(args[1]) {
+		return checkUsageMessage
+	}
+
+	repository, err := h.repository.GetUserSubscription(ctx, user, args[1])
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return checkNotSubscribedMessage
+	}
+
+	if err != nil {
+		logs.LogBotErrorMessage(update, err)
+
+		return ErrorMessage
+	}
+
+	now := time.Now()
+
+	if user.LastManualCheckAt != nil {
+		if wait := user.LastManualCheckAt.Add(h.cooldown).Sub(now); wait > 0 {
+			return i18n.Sprintf(user.Language, checkCooldownMessage, wait.Round(time.Second))
+		}
+	}
+
+	// the cooldown starts before the request, so failed checks count too
+	user.LastManualCheckAt = &now
+	if err := h.repository.UpdateUserSettings(ctx, user, "last_manual_check_at"); err != nil {
+		logs.LogBotErrorMessage(update, err)
+
+		return ErrorMessage
+	}
+
+	oldTag := repository.LatestTag
+
+	if err := h.checker.CheckRepository(github.WithInteractive(ctx), repository); err != nil {
+		logs.LogBotErrorMessage(update, err)
+
+		return checkFailedMessage
+	}
+
+	latestTag := i18n.T(user.Language, fallbackTag)
+	if repository.LatestTag != emptyString {
+		latestTag = html.EscapeString(repository.LatestTag)
+	}
+
+	answer := i18n.Sprintf(user.Language, checkCurrentMessage, repository.URL, repository.ShortName, latestTag)
+	if repository.LatestTag != oldTag {
+		answer += newLineTag + i18n.T(user.Language, checkNewReleaseMessage)
+	}
+
+	return answer
+}
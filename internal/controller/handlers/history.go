@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/config"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/controller/logs"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/entities"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/i18n"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/repo"
+)
+
+const (
+	historyUsageMessage string = "Usage: /history [github repository url] [count, up to 50]"
+	historyEmptyMessage string = "No releases recorded yet"
+	historyHeader       string = "Releases of <a href=\"%s\">%s</a>:"
+	historyLine         string = "<a href=\"%s\">%s</a> - %s"
+	historyGap          string = " (%s after the previous)"
+	historyDateLayout   string = "2006-01-02"
+	defaultHistorySize  int    = 10
+	maxHistorySize      int    = 50
+)
+
+type HistoryHandler struct {
+	repository *repo.Repository
+}
+
+func NewHistoryHandler(repository *repo.Repository) *HistoryHandler {
+	return &HistoryHandler{repository: repository}
+}
+
+// HistoryHandler shows the last releases of the repository with gaps between them, so the cadence is visible.
+func (h *HistoryHandler) HistoryHandler(
+	ctx context.Context,
+	update *models.Update,
+	user *entities.User,
+) string {
+	args := strings.Fields(update.Message.Text)
+	if len(args) < 2 || len(args) > 3 {
+		return historyUsageMessage
+	}
+
+	uriMatches := config.GithubPattern.FindStringSubmatch(args[1])
+	if len(uriMatches) == 0 {
+		return historyUsageMessage
+	}
+
+	limit := defaultHistorySize
+
+	if len(args) == 3 {
+		var err error
+
+		limit, err = strconv.Atoi(args[2])
+		if err != nil || limit < 1 || limit > maxHistorySize {
+			return historyUsageMessage
+		}
+	}
+
+	answer, err := h.formatHistory(ctx, user.Language, args[1], uriMatches[1], limit)
+	if err != nil {
+		logs.LogBotErrorMessage(update, err)
+
+		return ErrorMessage
+	}
+
+	return answer
+}
+
+func (h *HistoryHandler) formatHistory(
+	ctx context.Context,
+	language string,
+	repositoryURL string,
+	shortName string,
+	limit int,
+) (string, error) {
+	releases, err := h.repository.GetReleaseHistory(ctx, repositoryURL, limit)
+	if err != nil {
+		return emptyString, err
+	}
+
+	if len(releases) == 0 {
+		return i18n.T(language, historyEmptyMessage), nil
+	}
+
+	var answer strings.Builder
+
+	answer.WriteString(i18n.Sprintf(language, historyHeader, repositoryURL, shortName))
+
+	for index := range releases {
+		release := releases[index]
+		releasedAt := releaseTime(&release)
+
+		answer.WriteString(newLineTag)
+		answer.WriteString(i18n.Sprintf(
+			language,
+			historyLine,
+			release.URL,
+			html.EscapeString(release.TagName),
+			releasedAt.Format(historyDateLayout),
+		))
+
+		if index+1 < len(releases) {
+			if gap := releasedAt.Sub(releaseTime(&releases[index+1])); gap > 0 {
+				answer.WriteString(i18n.Sprintf(language, historyGap, formatDuration(language, gap)))
+			}
+		}
+	}
+
+	return answer.String(), nil
+}
+
+// releaseTime prefers the GitHub publication time, tags without releases have the detection time only.
+// The release history is ordered by the same time, so gaps are never negative.
+func releaseTime(release *entities.Release) time.Time {
+	if release.PublishedAt != nil {
+		return *release.PublishedAt
+	}
+
+	return release.DetectedAt
+}
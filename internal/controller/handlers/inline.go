@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"html"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/config"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/controller/logs"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/i18n"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/monitor/github"
+	"golang.org/x/time/rate"
+)
+
+const (
+	githubURLPrefix      string = "https://github.com/"
+	inlineResultID       string = "latest"
+	inlineReleaseMessage string = "Latest release of <a href=\"%s\">%s</a>: <a href=\"%s\">%s</a>"
+	inlinePublishedMask  string = "Published %s"
+	inlineTagDescription string = "Latest tag, the repository has no releases"
+	// inline queries are answered while the user is typing, so the lookup doesn't wait long for the API budget
+	inlineLookupTimeout time.Duration = 5 * time.Second
+	// every user may look up a few repositories in a row, then one every period
+	inlineLookupPeriod time.Duration = 2 * time.Second
+	inlineLookupBurst  int           = 5
+)
+
+// ReleaseLookup finds the latest release of any GitHub repository.
+type ReleaseLookup interface {
+	GetLatestRelease(ctx context.Context, shortName string) (github.ReleaseInfo, error)
+}
+
+type InlineHandler struct {
+	lookup   ReleaseLookup
+	throttle *inlineThrottle
+}
+
+func NewInlineHandler(lookup ReleaseLookup) *InlineHandler {
+	return &InlineHandler{lookup: lookup, throttle: &inlineThrottle{limiters: make(map[int64]*userLimiter)}}
+}
+
+// InlineQueryHandler answers "owner/repo" or a repository url with its latest release,
+// unknown repositories and incomplete queries have no results. The answer isn't final
+// when the lookup was throttled or failed, so it shouldn't be cached.
+func (h *InlineHandler) InlineQueryHandler(
+	ctx context.Context,
+	update *models.Update,
+) ([]models.InlineQueryResult, bool) {
+	query := update.InlineQuery
+
+	shortName, ok := parseRepositoryName(query.Query)
+	if !ok {
+		return nil, true
+	}
+
+	// inline queries from channels have no sender, the lookup cache protects the API budget then
+	if query.From != nil && !h.throttle.allow(query.From.ID) {
+		return nil, false
+	}
+
+	ctx, cancel := context.WithTimeout(github.WithInteractive(ctx), inlineLookupTimeout)
+	defer cancel()
+
+	releaseInfo, err := h.lookup.GetLatestRelease(ctx, shortName)
+	if errors.Is(err, github.ErrRepositoryNotFound) {
+		return nil, true
+	}
+
+	if err != nil {
+		logs.LogBotErrorMessage(update, err)
+
+		return nil, false
+	}
+
+	if releaseInfo.IsZero() {
+		return nil, true
+	}
+
+	// inline results are shown to users who may never have talked to the bot, so nothing is stored
+	language := i18n.DefaultLanguage
+	if query.From != nil {
+		language = i18n.Detect(query.From.LanguageCode)
+	}
+
+	return []models.InlineQueryResult{inlineReleaseResult(language, shortName, releaseInfo)}, true
+}
+
+func inlineReleaseResult(language string, shortName string, releaseInfo github.ReleaseInfo) models.InlineQueryResult {
+	description := i18n.T(language, inlineTagDescription)
+	if !releaseInfo.PublishedAt.IsZero() {
+		description = i18n.Sprintf(language, inlinePublishedMask, releaseInfo.PublishedAt.Format(historyDateLayout))
+	}
+
+	return &models.InlineQueryResultArticle{
+		ID:          inlineResultID,
+		Title:       shortName + " " + releaseInfo.TagName,
+		Description: description,
+		URL:         releaseInfo.SourceURL,
+		InputMessageContent: &models.InputTextMessageContent{
+			MessageText: i18n.Sprintf(
+				language,
+				inlineReleaseMessage,
+				githubURLPrefix+shortName,
+				shortName,
+				html.EscapeString(releaseInfo.SourceURL),
+				html.EscapeString(releaseInfo.TagName),
+			),
+			ParseMode: models.ParseModeHTML,
+		},
+	}
+}
+
+// inlineThrottle limits the lookups of every user, inline queries are sent while the user is typing.
+type inlineThrottle struct {
+	mu       sync.Mutex
+	limiters map[int64]*userLimiter
+}
+
+type userLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func (t *inlineThrottle) allow(userID int64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+
+	// the bucket of an idle user is full again, so idle users are forgotten and the map doesn't grow
+	idle := inlineLookupPeriod * time.Duration(inlineLookupBurst)
+	for limiterUserID, user := range t.limiters {
+		if now.Sub(user.lastSeen) > idle {
+			delete(t.limiters, limiterUserID)
+		}
+	}
+
+	user, ok := t.limiters[userID]
+	if !ok {
+		user = &userLimiter{limiter: rate.NewLimiter(rate.Every(inlineLookupPeriod), inlineLookupBurst)}
+		t.limiters[userID] = user
+	}
+
+	user.lastSeen = now
+
+	return user.limiter.AllowN(now, 1)
+}
+
+// parseRepositoryName accepts "owner/repo" and repository urls.
+func parseRepositoryName(query string) (string, bool) {
+	repositoryURL := githubURLPrefix + strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(query), githubURLPrefix), "/")
+
+	uriMatches := config.GithubPattern.FindStringSubmatch(repositoryURL)
+	if len(uriMatches) == 0 {
+		return emptyString, false
+	}
+
+	return uriMatches[1], true
+}
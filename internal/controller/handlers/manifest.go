@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/controller/logs"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/entities"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/i18n"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/importer"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/manifest"
+)
+
+const (
+	maxManifestSize            int64  = 1 << 20
+	maxUnresolvedListed        int    = 20
+	manifestTooLargeMessage    string = "Manifest file is too large :("
+	manifestUnsupportedMessage string = "Supported manifests: go.mod, package.json, requirements.txt, Cargo.toml, pom.xml"
+	manifestTooManyMessage     string = "Manifest has too many dependencies :("
+	manifestResolvingMessage   string = "Looking for %d dependencies on GitHub, the result will follow in a reply..."
+	manifestImportedMessage    string = "Subscribed to %d repositories from %d dependencies!"
+	manifestUnresolvedHeader   string = "Not found on GitHub: "
+	manifestUnresolvedMore     string = " and %d more"
+	listDelim                  string = ", "
+	// manifestImportTimeout bounds the background import, registries are slow on large manifests
+	manifestImportTimeout time.Duration = 5 * time.Minute
+)
+
+type FileDownloader interface {
+	DownloadFile(ctx context.Context, fileID string, maxSize int64) ([]byte, error)
+}
+
+// MessageReplier answers the message later, when a background job is done.
+type MessageReplier interface {
+	ReplyMessage(ctx context.Context, message *models.Message, answer string) error
+}
+
+type ManifestHandler struct {
+	importer   *importer.Importer
+	downloader FileDownloader
+	replier    MessageReplier
+}
+
+func NewManifestHandler(
+	repositoryImporter *importer.Importer,
+	downloader FileDownloader,
+	replier MessageReplier,
+) *ManifestHandler {
+	return &ManifestHandler{importer: repositoryImporter, downloader: downloader, replier: replier}
+}
+
+func (h *ManifestHandler) UploadManifestHandler(
+	ctx context.Context,
+	update *models.Update,
+	user *entities.User,
+) string {
+	document := update.Message.Document
+	if document.FileSize > maxManifestSize {
+		return manifestTooLargeMessage
+	}
+
+	content, err := h.downloader.DownloadFile(ctx, document.FileID, maxManifestSize)
+	if err != nil {
+		logs.LogBotErrorMessage(update, err)
+
+		return ErrorMessage
+	}
+
+	dependencies, err := h.importer.ParseManifest(document.FileName, content)
+	if err != nil {
+		logs.LogBotErrorMessage(update, err)
+
+		switch {
+		case errors.Is(err, manifest.ErrUnsupportedManifest):
+			return manifestUnsupportedMessage
+		case errors.Is(err, importer.ErrTooManyDependencies):
+			return manifestTooManyMessage
+		default:
+			return ErrorMessage
+		}
+	}
+
+	// every dependency is looked up in its registry, so the result is sent when all of them are resolved
+	go h.importManifest(ctx, update, *user, dependencies)
+
+	return i18n.Sprintf(user.Language, manifestResolvingMessage, len(dependencies))
+}
+
+func (h *ManifestHandler) importManifest(
+	ctx context.Context,
+	update *models.Update,
+	user entities.User,
+	dependencies []manifest.Dependency,
+) {
+	ctx, cancel := context.WithTimeout(ctx, manifestImportTimeout)
+	defer cancel()
+
+	answer := i18n.T(user.Language, ErrorMessage)
+
+	result, err := h.importer.ImportManifest(ctx, &user, dependencies)
+	if err != nil {
+		logs.LogBotErrorMessage(update, err)
+	} else {
+		answer = formatManifestResult(&user, &result)
+	}
+
+	if err := h.replier.ReplyMessage(ctx, update.Message, answer); err != nil {
+		logs.LogBotErrorMessage(update, err)
+
+		return
+	}
+
+	logs.LogBotOutgoingMessage(update, answer)
+}
+
+func formatManifestResult(user *entities.User, result *importer.ManifestImportResult) string {
+	var answer strings.Builder
+
+	answer.WriteString(i18n.Sprintf(user.Language, manifestImportedMessage, result.Subscribed, result.Dependencies))
+
+	if len(result.Unresolved) > 0 {
+		unresolved := result.Unresolved
+		if len(unresolved) > maxUnresolvedListed {
+			unresolved = unresolved[:maxUnresolvedListed]
+		}
+
+		answer.WriteString(newLineTag)
+		answer.WriteString(i18n.T(user.Language, manifestUnresolvedHeader))
+		answer.WriteString(html.EscapeString(strings.Join(unresolved, listDelim)))
+
+		if len(result.Unresolved) > maxUnresolvedListed {
+			answer.WriteString(i18n.Sprintf(user.Language, manifestUnresolvedMore, len(result.Unresolved)-maxUnresolvedListed))
+		}
+	}
+
+	return answer.String()
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/controller/logs"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/entities"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/i18n"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/repo"
+	"gorm.io/gorm"
+)
+
+// Callback actions of the release notification buttons, the callback data is "action:repository id:signature".
+const (
+	CallbackHistory     string = "history"
+	CallbackMute        string = "mute"
+	CallbackUnsubscribe string = "unsubscribe"
+)
+
+const (
+	muteDuration                   = 7 * hoursPerDayDuration
+	mutedMessage            string = "Notifications about <a href=\"%s\">%s</a> are muted until %s"
+	notSubscribedMessage    string = "You are not subscribed to <a href=\"%s\">%s</a>"
+	actionUnsubscribed      string = "Successfully unsubscribed from <a href=\"%s\">%s</a>!"
+	repositoryGoneMessage   string = "The repository isn't tracked anymore"
+	muteDateLayout          string = "2006-01-02 15:04"
+	callbackRepositoryIndex int    = 1
+)
+
+type ReleaseActionsHandler struct {
+	repository *repo.Repository
+	history    *HistoryHandler
+}
+
+func NewReleaseActionsHandler(repository *repo.Repository, history *HistoryHandler) *ReleaseActionsHandler {
+	return &ReleaseActionsHandler{repository: repository, history: history}
+}
+
+func (h *ReleaseActionsHandler) HistoryHandler(
+	ctx context.Context,
+	update *models.Update,
+	user *entities.User,
+) string {
+	repository, answer := h.callbackRepository(ctx, update)
+	if repository == nil {
+		return answer
+	}
+
+	answer, err := h.history.formatHistory(ctx, user.Language, repository.URL, repository.ShortName, defaultHistorySize)
+	if err != nil {
+		logs.LogBotErrorMessage(update, err)
+
+		return ErrorMessage
+	}
+
+	return answer
+}
+
+func (h *ReleaseActionsHandler) MuteHandler(
+	ctx context.Context,
+	update *models.Update,
+	user *entities.User,
+) string {
+	repository, answer := h.callbackRepository(ctx, update)
+	if repository == nil {
+		return answer
+	}
+
+	until := time.Now().Add(muteDuration)
+
+	muted, err := h.repository.MuteUserSubscription(ctx, user, repository.ID, until)
+	if err != nil {
+		logs.LogBotErrorMessage(update, err)
+
+		return ErrorMessage
+	}
+
+	if !muted {
+		return i18n.Sprintf(user.Language, notSubscribedMessage, repository.URL, repository.ShortName)
+	}
+
+	return i18n.Sprintf(
+		user.Language,
+		mutedMessage,
+		repository.URL,
+		repository.ShortName,
+		until.In(user.Location()).Format(muteDateLayout),
+	)
+}
+
+func (h *ReleaseActionsHandler) UnsubscribeHandler(
+	ctx context.Context,
+	update *models.Update,
+	user *entities.User,
+) string {
+	repository, answer := h.callbackRepository(ctx, update)
+	if repository == nil {
+		return answer
+	}
+
+	if err := h.repository.RemoveUserSubscriptionByID(ctx, user, repository.ID); err != nil {
+		logs.LogBotErrorMessage(update, err)
+
+		return ErrorMessage
+	}
+
+	return i18n.Sprintf(user.Language, actionUnsubscribed, repository.URL, repository.ShortName)
+}
+
+// callbackRepository loads the repository from the verified callback data,
+// the answer is returned instead when it can't be loaded.
+func (h *ReleaseActionsHandler) callbackRepository(
+	ctx context.Context,
+	update *models.Update,
+) (*entities.Repository, string) {
+	parts := strings.Split(update.CallbackQuery.Data, ":")
+	if len(parts) <= callbackRepositoryIndex {
+		return nil, ErrorMessage
+	}
+
+	repositoryID, err := strconv.ParseUint(parts[callbackRepositoryIndex], 10, 0)
+	if err != nil {
+		logs.LogBotErrorMessage(update, err)
+
+		return nil, ErrorMessage
+	}
+
+	repository, err := h.repository.GetRepositoryByID(ctx, uint(repositoryID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, repositoryGoneMessage
+	}
+
+	if err != nil {
+		logs.LogBotErrorMessage(update, err)
+
+		return nil, ErrorMessage
+	}
+
+	return repository, emptyString
+}
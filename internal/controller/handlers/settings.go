@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/controller/logs"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/entities"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/i18n"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/repo"
+)
+
+const (
+	deliveryUsageMessage   string = "Usage: /delivery [instant|daily|weekly] [hour 0-23] [weekday mon-sun]"
+	deliveryInstantMessage string = "Delivery mode: instant"
+	deliveryDailyMessage   string = "Delivery mode: daily digest at %02d:00 %s"
+	deliveryWeeklyMessage  string = "Delivery mode: weekly digest on %s at %02d:00 %s"
+	timezoneUsageMessage   string = "Usage: /timezone [IANA time zone, e.g. Europe/Berlin]"
+	timezoneMessage        string = "Time zone: %s"
+	quietUsageMessage      string = "Usage: /quiet [HH:MM-HH:MM|off] [hold|silent]"
+	quietDisabledMessage   string = "Quiet hours: off"
+	quietHoldMessage       string = "Quiet hours: %s-%s %s, notifications are held until the end"
+	quietSilentMessage     string = "Quiet hours: %s-%s %s, notifications are sent silently"
+	quietOffOption         string = "off"
+	languageMessage        string = "Language: %s"
+	languageUsageMessage   string = "Usage: /language [en|ru]"
+	clockLayout            string = "15:04"
+	hoursPerDay            int    = 24
+)
+
+type SettingsHandler struct {
+	repository *repo.Repository
+}
+
+func NewSettingsHandler(repository *repo.Repository) *SettingsHandler {
+	return &SettingsHandler{repository: repository}
+}
+
+func (h *SettingsHandler) DeliveryHandler(
+	ctx context.Context,
+	update *models.Update,
+	user *entities.User,
+) string {
+	args := strings.Fields(update.Message.Text)
+	if len(args) == 1 {
+		return formatDeliveryMode(user)
+	}
+
+	if !parseDeliveryMode(args[1:], user) {
+		return deliveryUsageMessage
+	}
+
+	err := h.repository.UpdateUserSettings(ctx, user, "delivery_mode", "digest_hour", "digest_weekday")
+	if err != nil {
+		logs.LogBotErrorMessage(update, err)
+
+		return ErrorMessage
+	}
+
+	return formatDeliveryMode(user)
+}
+
+func parseDeliveryMode(args []string, user *entities.User) bool {
+	mode := strings.ToLower(args[0])
+
+	switch mode {
+	case entities.DeliveryModeInstant, entities.DeliveryModeDaily, entities.DeliveryModeWeekly:
+		user.DeliveryMode = mode
+	default:
+		return false
+	}
+
+	if len(args) > 1 {
+		hour, err := strconv.Atoi(args[1])
+		if err != nil || hour < 0 || hour >= hoursPerDay {
+			return false
+		}
+
+		user.DigestHour = hour
+	}
+
+	if len(args) > 2 { //nolint:mnd // mode, hour and weekday arguments
+		weekday, ok := parseWeekday(args[2])
+		if !ok {
+			return false
+		}
+
+		user.DigestWeekday = int(weekday)
+	}
+
+	return true
+}
+
+// parseWeekday accepts full or three-letter English weekday names.
+func parseWeekday(name string) (time.Weekday, bool) {
+	name = strings.ToLower(name)
+
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		fullName := strings.ToLower(weekday.String())
+		if name == fullName || name == fullName[:3] {
+			return weekday, true
+		}
+	}
+
+	return time.Sunday, false
+}
+
+func formatDeliveryMode(user *entities.User) string {
+	switch user.DeliveryMode {
+	case entities.DeliveryModeDaily:
+		return i18n.Sprintf(user.Language, deliveryDailyMessage, user.DigestHour, user.TimeZone)
+	case entities.DeliveryModeWeekly:
+		weekday := i18n.T(user.Language, time.Weekday(user.DigestWeekday).String())
+
+		return i18n.Sprintf(user.Language, deliveryWeeklyMessage, weekday, user.DigestHour, user.TimeZone)
+	default:
+		return deliveryInstantMessage
+	}
+}
+
+func (h *SettingsHandler) TimezoneHandler(
+	ctx context.Context,
+	update *models.Update,
+	user *entities.User,
+) string {
+	args := strings.Fields(update.Message.Text)
+	if len(args) == 1 {
+		return i18n.Sprintf(user.Language, timezoneMessage, user.TimeZone)
+	}
+
+	if len(args) != 2 { //nolint:mnd // command and time zone
+		return timezoneUsageMessage
+	}
+
+	location, err := time.LoadLocation(args[1])
+	// "Local" is the server time zone, it means nothing to the user
+	if err != nil || location == time.Local {
+		return timezoneUsageMessage
+	}
+
+	user.TimeZone = location.String()
+
+	if err := h.repository.UpdateUserSettings(ctx, user, "time_zone"); err != nil {
+		logs.LogBotErrorMessage(update, err)
+
+		return ErrorMessage
+	}
+
+	return i18n.Sprintf(user.Language, timezoneMessage, user.TimeZone)
+}
+
+func (h *SettingsHandler) LanguageHandler(
+	ctx context.Context,
+	update *models.Update,
+	user *entities.User,
+) string {
+	args := strings.Fields(update.Message.Text)
+	if len(args) == 1 {
+		return i18n.Sprintf(user.Language, languageMessage, user.Language)
+	}
+
+	language := strings.ToLower(args[1])
+	if len(args) != 2 || !i18n.IsSupported(language) { //nolint:mnd // command and language
+		return languageUsageMessage
+	}
+
+	user.Language = language
+
+	if err := h.repository.UpdateUserSettings(ctx, user, "language"); err != nil {
+		logs.LogBotErrorMessage(update, err)
+
+		return ErrorMessage
+	}
+
+	return i18n.Sprintf(user.Language, languageMessage, user.Language)
+}
+
+func (h *SettingsHandler) QuietHoursHandler(
+	ctx context.Context,
+	update *models.Update,
+	user *entities.User,
+) string {
+	args := strings.Fields(update.Message.Text)
+	if len(args) == 1 {
+		return formatQuietHours(user)
+	}
+
+	if !parseQuietHours(args[1:], user) {
+		return quietUsageMessage
+	}
+
+	if err := h.repository.UpdateUserSettings(ctx, user, "quiet_start", "quiet_end", "quiet_mode"); err != nil {
+		logs.LogBotErrorMessage(update, err)
+
+		return ErrorMessage
+	}
+
+	return formatQuietHours(user)
+}
+
+func parseQuietHours(args []string, user *entities.User) bool {
+	if strings.EqualFold(args[0], quietOffOption) {
+		user.QuietStart, user.QuietEnd = 0, 0
+
+		return len(args) == 1
+	}
+
+	startText, endText, found := strings.Cut(args[0], "-")
+	if !found {
+		return false
+	}
+
+	start, startErr := time.Parse(clockLayout, startText)
+	end, endErr := time.Parse(clockLayout, endText)
+
+	if startErr != nil || endErr != nil || start.Equal(end) {
+		return false
+	}
+
+	user.QuietStart = start.Hour()*entities.MinutesPerHour + start.Minute()
+	user.QuietEnd = end.Hour()*entities.MinutesPerHour + end.Minute()
+
+	if len(args) > 1 {
+		mode := strings.ToLower(args[1])
+		if mode != entities.QuietModeHold && mode != entities.QuietModeSilent {
+			return false
+		}
+
+		user.QuietMode = mode
+	}
+
+	return len(args) <= 2 //nolint:mnd // window and mode arguments
+}
+
+func formatQuietHours(user *entities.User) string {
+	if user.QuietStart == user.QuietEnd {
+		return quietDisabledMessage
+	}
+
+	start := formatClock(user.QuietStart)
+	end := formatClock(user.QuietEnd)
+
+	if user.QuietMode == entities.QuietModeSilent {
+		return i18n.Sprintf(user.Language, quietSilentMessage, start, end, user.TimeZone)
+	}
+
+	return i18n.Sprintf(user.Language, quietHoldMessage, start, end, user.TimeZone)
+}
+
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/entities.MinutesPerHour, minutes%entities.MinutesPerHour)
+}
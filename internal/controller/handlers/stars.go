@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/config"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/controller/logs"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/entities"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/i18n"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/importer"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/monitor/github"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/repo"
+)
+
+const (
+	starsUsageMessage        string = "Usage: /import_stars [github username] [sync|stop]"
+	starsImportedMessage     string = "Imported %d starred repositories!"
+	starsSyncEnabledMessage  string = "New stars of %s will be subscribed automatically."
+	starsSyncDisabledMessage string = "Stars sync for %s is stopped."
+	starsSyncOption          string = "sync"
+	starsStopOption          string = "stop"
+)
+
+type StarsHandler struct {
+	repository *repo.Repository
+	importer   *importer.Importer
+}
+
+func NewStarsHandler(repository *repo.Repository, repositoryImporter *importer.Importer) *StarsHandler {
+	return &StarsHandler{repository: repository, importer: repositoryImporter}
+}
+
+func (h *StarsHandler) ImportStarsHandler(
+	ctx context.Context,
+	update *models.Update,
+	user *entities.User,
+) string {
+	args := strings.Fields(update.Message.Text)
+	if len(args) < 2 || len(args) > 3 || !config.GithubUsernamePattern.MatchString(args[1]) {
+		return starsUsageMessage
+	}
+
+	githubUsername := args[1]
+
+	option := emptyString
+	if len(args) == 3 {
+		option = args[2]
+	}
+
+	switch option {
+	case starsStopOption:
+		if err := h.repository.RemoveStarredImport(ctx, user, githubUsername); err != nil {
+			logs.LogBotErrorMessage(update, err)
+
+			return ErrorMessage
+		}
+
+		return i18n.Sprintf(user.Language, starsSyncDisabledMessage, githubUsername)
+	case starsSyncOption, emptyString:
+	default:
+		return starsUsageMessage
+	}
+
+	messageThreadID := TopicID(update.Message)
+
+	count, err := h.importer.ImportStarredRepositories(github.WithInteractive(ctx), user, githubUsername, messageThreadID)
+	if err != nil {
+		logs.LogBotErrorMessage(update, err)
+
+		return ErrorMessage
+	}
+
+	answer := i18n.Sprintf(user.Language, starsImportedMessage, count)
+
+	if option == starsSyncOption {
+		if err := h.repository.AddStarredImport(ctx, user, githubUsername, messageThreadID); err != nil {
+			logs.LogBotErrorMessage(update, err)
+
+			return ErrorMessage
+		}
+
+		answer += newLineTag + i18n.Sprintf(user.Language, starsSyncEnabledMessage, githubUsername)
+	}
+
+	return answer
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"context"
+	"html"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/controller/logs"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/entities"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/i18n"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/repo"
+)
+
+const (
+	statusSummaryMessage string = "Subscriptions health: %d ok, %d failing, %d waiting for the first check"
+	statusWaitingLine    string = "⏳ <a href=\"%s\">%s</a> - waiting for the first check"
+	statusAdminOnly      string = "This command is available for admins only"
+	statusNoFailures     string = "No failing repositories :)"
+	statusReportHeader   string = "The most failing repositories:"
+	statusReportLine     string = "%d. <a href=\"%s\">%s</a> - %d failed checks, last success: %s, last check: %s"
+	statusMoreLines      string = "\n… and %d more"
+	neverLabel           string = "never"
+	agoMask              string = "%s ago"
+	failingReportSize    int    = 10
+	hoursPerDayDuration         = 24 * time.Hour
+
+	statusFailingLine string = "⚠️ <a href=\"%s\">%s</a> - %d failed checks, last success: %s, " +
+		"last release detected: %s\n<code>%s</code>"
+	statusHealthyLine string = "✅ <a href=\"%s\">%s</a> - last release detected: %s"
+)
+
+type StatusHandler struct {
+	repository *repo.Repository
+	adminIDs   []int64
+}
+
+func NewStatusHandler(repository *repo.Repository, adminIDs []int64) *StatusHandler {
+	return &StatusHandler{repository: repository, adminIDs: adminIDs}
+}
+
+// StatusHandler summarizes the health of the user subscriptions and lists problematic ones.
+func (h *StatusHandler) StatusHandler(
+	ctx context.Context,
+	update *models.Update,
+	user *entities.User,
+) string {
+	repositories, err := h.repository.GetAllUserSubscriptions(ctx, user)
+	if err != nil {
+		logs.LogBotErrorMessage(update, err)
+
+		return ErrorMessage
+	}
+
+	if len(repositories) == 0 {
+		return emptySubscriptions
+	}
+
+	var (
+		healthy, failing, waiting int
+		lines, healthyLines       []string
+	)
+
+	now := time.Now()
+
+	for index := range repositories {
+		repository := repositories[index]
+
+		switch {
+		case repository.ConsecutiveFailures > 0:
+			lines = append(lines, i18n.Sprintf(
+				user.Language,
+				statusFailingLine,
+				repository.URL,
+				repository.ShortName,
+				repository.ConsecutiveFailures,
+				formatAge(user.Language, now, repository.LastSuccessAt),
+				formatAge(user.Language, now, repository.LastDetectedAt),
+				html.EscapeString(repository.LastError),
+			))
+			failing++
+		case repository.LastCheckedAt == nil:
+			lines = append(lines, i18n.Sprintf(user.Language, statusWaitingLine, repository.URL, repository.ShortName))
+			waiting++
+		default:
+			healthyLines = append(healthyLines, i18n.Sprintf(
+				user.Language,
+				statusHealthyLine,
+				repository.URL,
+				repository.ShortName,
+				formatAge(user.Language, now, repository.LastDetectedAt),
+			))
+			healthy++
+		}
+	}
+
+	// problems go first, so they aren't cut off by the message limit
+	lines = append(lines, healthyLines...)
+
+	return joinLines(user.Language, i18n.Sprintf(user.Language, statusSummaryMessage, healthy, failing, waiting), lines)
+}
+
+// FailingReportHandler is the admin view of the repositories with the longest series of failed checks.
+func (h *StatusHandler) FailingReportHandler(
+	ctx context.Context,
+	update *models.Update,
+	user *entities.User,
+) string {
+	// in groups the user is the chat, so the sender is checked
+	if update.Message.From == nil || !slices.Contains(h.adminIDs, update.Message.From.ID) {
+		return statusAdminOnly
+	}
+
+	repositories, err := h.repository.GetFailingRepositories(ctx, failingReportSize)
+	if err != nil {
+		logs.LogBotErrorMessage(update, err)
+
+		return ErrorMessage
+	}
+
+	if len(repositories) == 0 {
+		return statusNoFailures
+	}
+
+	now := time.Now()
+	lines := make([]string, 0, len(repositories))
+
+	for index := range repositories {
+		repository := repositories[index]
+
+		lines = append(lines, i18n.Sprintf(
+			user.Language,
+			statusReportLine,
+			index+1,
+			repository.URL,
+			repository.ShortName,
+			repository.ConsecutiveFailures,
+			formatAge(user.Language, now, repository.LastSuccessAt),
+			formatAge(user.Language, now, repository.LastCheckedAt),
+		)+newLineTag+"<code>"+html.EscapeString(repository.LastError)+"</code>")
+	}
+
+	return joinLines(user.Language, i18n.T(user.Language, statusReportHeader), lines)
+}
+
+// joinLines adds lines to the header while the answer fits one message, the rest are counted.
+func joinLines(language string, header string, lines []string) string {
+	var answer strings.Builder
+
+	answer.WriteString(header)
+
+	// room for the counter of the rest lines is reserved, it's the longest with all lines
+	reserved := len(i18n.Sprintf(language, statusMoreLines, len(lines)))
+
+	for index, line := range lines {
+		if answer.Len()+len(newLineTag)+len(line)+reserved > entities.MaxMessageLength {
+			answer.WriteString(i18n.Sprintf(language, statusMoreLines, len(lines)-index))
+
+			break
+		}
+
+		answer.WriteString(newLineTag)
+		answer.WriteString(line)
+	}
+
+	return answer.String()
+}
+
+func formatAge(language string, now time.Time, moment *time.Time) string {
+	if moment == nil {
+		return i18n.T(language, neverLabel)
+	}
+
+	return i18n.Sprintf(language, agoMask, formatDuration(language, now.Sub(*moment)))
+}
+
+func formatDuration(language string, duration time.Duration) string {
+	switch {
+	case duration >= hoursPerDayDuration:
+		return i18n.Sprintf(language, "%dd", duration/hoursPerDayDuration)
+	case duration >= time.Hour:
+		return i18n.Sprintf(language, "%dh", duration/time.Hour)
+	default:
+		return i18n.Sprintf(language, "%dm", duration/time.Minute)
+	}
+}
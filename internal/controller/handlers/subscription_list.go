@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/controller/logs"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/entities"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/i18n"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/repo"
+)
+
+// CallbackSubscriptionsPage is the callback action of the subscription list buttons,
+// the callback data is "action:page:sort:filter:signature".
+const CallbackSubscriptionsPage string = "subs"
+
+const (
+	emptySubscriptions       string = "Subscriptions: empty"
+	emptyFilteredMessage     string = "No subscriptions match \"%s\""
+	subscriptionsPageHeader  string = "Subscriptions %d-%d of %d:"
+	subscriptionsUsage       string = "Usage: /my_subscriptions [--sort=name|release] [filter]"
+	sortOption               string = "--sort="
+	subscriptionsPageSize    int    = 20
+	maxSubscriptionsFilter   int    = 32
+	subscriptionsQueryFields int    = 4
+)
+
+// SubscriptionsQuery selects a page of the subscription list, it's kept in the callback data of the page buttons.
+type SubscriptionsQuery struct {
+	Filter string
+	Sort   repo.SubscriptionsSort
+	Page   int
+}
+
+// SubscriptionsPage is a rendered page of the subscription list.
+type SubscriptionsPage struct {
+	Query SubscriptionsQuery
+	Text  string
+	// Pages is the number of pages, it's zero when there is no list to show
+	Pages int
+}
+
+// ParseSubscriptionsQuery parses "/my_subscriptions [--sort=name|release] [filter]".
+func ParseSubscriptionsQuery(text string) (SubscriptionsQuery, bool) {
+	query := SubscriptionsQuery{Sort: repo.SortByName}
+
+	for _, field := range strings.Fields(text)[1:] {
+		switch {
+		case strings.HasPrefix(field, sortOption):
+			query.Sort = repo.SubscriptionsSort(strings.TrimPrefix(field, sortOption))
+			if query.Sort != repo.SortByName && query.Sort != repo.SortByRelease {
+				return query, false
+			}
+		case query.Filter == emptyString:
+			query.Filter = strings.ToLower(field)
+		default:
+			return query, false
+		}
+	}
+
+	// the filter is kept in the callback data, which is limited to 64 bytes
+	return query, len(query.Filter) <= maxSubscriptionsFilter
+}
+
+// ParseSubscriptionsCallback parses the "action:page:sort:filter" payload of the page buttons.
+func ParseSubscriptionsCallback(payload string) (SubscriptionsQuery, bool) {
+	fields := strings.SplitN(payload, ":", subscriptionsQueryFields)
+	if len(fields) != subscriptionsQueryFields {
+		return SubscriptionsQuery{}, false
+	}
+
+	page, err := strconv.Atoi(fields[1])
+	if err != nil || page < 0 {
+		return SubscriptionsQuery{}, false
+	}
+
+	return SubscriptionsQuery{Filter: fields[3], Sort: repo.SubscriptionsSort(fields[2]), Page: page}, true
+}
+
+// Payload returns the callback payload of the query, see ParseSubscriptionsCallback.
+func (q SubscriptionsQuery) Payload() string {
+	return strings.Join([]string{CallbackSubscriptionsPage, strconv.Itoa(q.Page), string(q.Sort), q.Filter}, ":")
+}
+
+func (h *SubscriptionsHandler) MySubscriptionsHandler(
+	ctx context.Context,
+	update *models.Update,
+	user *entities.User,
+) SubscriptionsPage {
+	query, ok := ParseSubscriptionsQuery(update.Message.Text)
+	if !ok {
+		return SubscriptionsPage{Query: query, Text: subscriptionsUsage}
+	}
+
+	return h.SubscriptionsPage(ctx, update, user, query)
+}
+
+// SubscriptionsPage renders the requested page, the last page is shown when the list got shorter.
+func (h *SubscriptionsHandler) SubscriptionsPage(
+	ctx context.Context,
+	update *models.Update,
+	user *entities.User,
+	query SubscriptionsQuery,
+) SubscriptionsPage {
+	offset := query.Page * subscriptionsPageSize
+
+	repositories, total, err := h.repository.GetUserSubscriptionsPage(
+		ctx, user, query.Filter, query.Sort, offset, subscriptionsPageSize,
+	)
+	if err == nil && len(repositories) == 0 && total > 0 {
+		query.Page = int((total - 1) / int64(subscriptionsPageSize))
+		offset = query.Page * subscriptionsPageSize
+		repositories, total, err = h.repository.GetUserSubscriptionsPage(
+			ctx, user, query.Filter, query.Sort, offset, subscriptionsPageSize,
+		)
+	}
+
+	if err != nil {
+		logs.LogBotErrorMessage(update, err)
+
+		return SubscriptionsPage{Query: query, Text: ErrorMessage}
+	}
+
+	if total == 0 {
+		if query.Filter != emptyString {
+			return SubscriptionsPage{
+				Query: query,
+				Text:  i18n.Sprintf(user.Language, emptyFilteredMessage, html.EscapeString(query.Filter)),
+			}
+		}
+
+		return SubscriptionsPage{Query: query, Text: emptySubscriptions}
+	}
+
+	var answer strings.Builder
+
+	answer.WriteString(i18n.Sprintf(user.Language, subscriptionsPageHeader, offset+1, offset+len(repositories), total))
+
+	for index := range repositories {
+		repository := repositories[index]
+
+		latestTag := i18n.T(user.Language, fallbackTag)
+		if repository.LatestTag != emptyString {
+			latestTag = html.EscapeString(repository.LatestTag)
+		}
+
+		answer.WriteString(newLineTag)
+		answer.WriteString(latestTag)
+		answer.WriteString(delim)
+		answer.WriteString(repository.URL)
+
+		if query.Sort == repo.SortByRelease && repository.LastReleaseAt != nil {
+			answer.WriteString(" (" + repository.LastReleaseAt.In(user.Location()).Format(historyDateLayout) + ")")
+		}
+	}
+
+	pages := int((total + int64(subscriptionsPageSize) - 1) / int64(subscriptionsPageSize))
+
+	return SubscriptionsPage{Query: query, Text: answer.String(), Pages: pages}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/repo"
+)
+
+func TestParseSubscriptionsQuery(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text  string
+		query SubscriptionsQuery
+		ok    bool
+	}{
+		{text: "/my_subscriptions", query: SubscriptionsQuery{Sort: repo.SortByName}, ok: true},
+		{text: "/my_subscriptions Go", query: SubscriptionsQuery{Filter: "go", Sort: repo.SortByName}, ok: true},
+		{text: "/my_subscriptions --sort=release", query: SubscriptionsQuery{Sort: repo.SortByRelease}, ok: true},
+		{
+			text:  "/my_subscriptions bot --sort=release",
+			query: SubscriptionsQuery{Filter: "bot", Sort: repo.SortByRelease},
+			ok:    true,
+		},
+		{text: "/my_subscriptions --sort=date", ok: false},
+		{text: "/my_subscriptions go bot", ok: false},
+		{
+			text:  "/my_subscriptions " + strings.Repeat("a", maxSubscriptionsFilter),
+			query: SubscriptionsQuery{Filter: strings.Repeat("a", maxSubscriptionsFilter), Sort: repo.SortByName},
+			ok:    true,
+		},
+		{text: "/my_subscriptions " + strings.Repeat("a", maxSubscriptionsFilter+1), ok: false},
+	}
+
+	for _, test := range tests {
+		query, ok := ParseSubscriptionsQuery(test.text)
+		if ok != test.ok {
+			t.Errorf("ParseSubscriptionsQuery(%q) ok = %t, want %t", test.text, ok, test.ok)
+
+			continue
+		}
+
+		if ok && query != test.query {
+			t.Errorf("ParseSubscriptionsQuery(%q) = %+v, want %+v", test.text, query, test.query)
+		}
+	}
+}
+
+func TestParseSubscriptionsCallback(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		payload string
+		query   SubscriptionsQuery
+		ok      bool
+	}{
+		{payload: "subs:0:name:", query: SubscriptionsQuery{Sort: repo.SortByName}, ok: true},
+		{
+			payload: "subs:3:release:go:bot",
+			query:   SubscriptionsQuery{Filter: "go:bot", Sort: repo.SortByRelease, Page: 3},
+			ok:      true,
+		},
+		{payload: "subs:0:name", ok: false},
+		{payload: "subs:x:name:", ok: false},
+		{payload: "subs:-1:name:", ok: false},
+	}
+
+	for _, test := range tests {
+		query, ok := ParseSubscriptionsCallback(test.payload)
+		if ok != test.ok || query != test.query {
+			t.Errorf(
+				"ParseSubscriptionsCallback(%q) = %+v, %t, want %+v, %t",
+				test.payload, query, ok, test.query, test.ok,
+			)
+		}
+	}
+}
+
+func TestSubscriptionsQueryPayload(t *testing.T) {
+	t.Parallel()
+
+	queries := []SubscriptionsQuery{
+		{Sort: repo.SortByName},
+		{Filter: "go-telegram", Sort: repo.SortByRelease, Page: 12},
+	}
+
+	for _, query := range queries {
+		parsed, ok := ParseSubscriptionsCallback(query.Payload())
+		if !ok || parsed != query {
+			t.Errorf("ParseSubscriptionsCallback(%q) = %+v, %t, want %+v", query.Payload(), parsed, ok, query)
+		}
+	}
+}
@@ -2,84 +2,196 @@ package handlers
 
 import (
 	"context"
+	"html"
+	"path"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/go-telegram/bot/models"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/config"
 	"github.com/soltanoff/go_github_release_monitor_bot/internal/controller/logs"
 	"github.com/soltanoff/go_github_release_monitor_bot/internal/entities"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/i18n"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/importer"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/monitor/github"
 	"github.com/soltanoff/go_github_release_monitor_bot/internal/repo"
 )
 
+// ErrorMessage is the answer to a command which failed for an internal reason.
+const ErrorMessage string = "Some error caused, please try latter :("
+
 const (
-	errorMessage               string = "Some error caused, please try latter :("
-	emptySubscriptions         string = "Subscriptions: empty"
 	emptyString                string = ""
-	subscriptionHeader         string = "Subscriptions: "
 	fallbackTag                string = "fetch in progress"
 	newLineTag                 string = "\n"
 	delim                      string = " - "
 	successSubscribedMessage   string = "Successfully subscribed!"
 	successUnsubscribedMessage string = "Successfully unsubscribed!"
+	invalidPatternMessage      string = "Invalid repository name pattern :("
+	includeOption              string = "--include="
+	excludeOption              string = "--exclude="
+	skipArchivedOption         string = "--skip-archived"
+	skipForksOption            string = "--skip-forks"
+	fetchedRepositoryLine      string = "<a href=\"%s\">%s</a>: %s"
+	fetchedReleaseLine         string = "  • <a href=\"%s\">%s</a>"
+	fetchPostponedLine         string = "<a href=\"%s\">%s</a>: will be fetched soon"
+	removeAllConfirmation      string = "yes"
+	removeAllNotConfirmed      string = "Nothing removed, send /remove_all_subscriptions yes to confirm"
+	maxImmediateFetches        int    = 3
+	recentReleasesCount        int    = 5
 )
 
 type SubscriptionsHandler struct {
-	repository *repo.Repository
+	repository   *repo.Repository
+	importer     *importer.Importer
+	checker      RepositoryChecker
+	fetchTimeout time.Duration
 }
 
-func NewSubscriptionsHandler(repository *repo.Repository) *SubscriptionsHandler {
-	return &SubscriptionsHandler{repository: repository}
+func NewSubscriptionsHandler(
+	repository *repo.Repository,
+	repositoryImporter *importer.Importer,
+	checker RepositoryChecker,
+	fetchTimeout time.Duration,
+) *SubscriptionsHandler {
+	return &SubscriptionsHandler{
+		repository:   repository,
+		importer:     repositoryImporter,
+		checker:      checker,
+		fetchTimeout: fetchTimeout,
+	}
 }
 
-func (h *SubscriptionsHandler) MySubscriptionsHandler(
+func (h *SubscriptionsHandler) SubscribeHandler(
 	ctx context.Context,
 	update *models.Update,
 	user *entities.User,
 ) string {
-	selectedRepository, err := h.repository.GetAllUserSubscriptions(ctx, user)
+	messageThreadID := TopicID(update.Message)
+
+	ownerSubscriptions, repositoryURLs, ok := parseOwnerSubscriptions(update.Message.Text, user, messageThreadID)
+	if !ok {
+		return invalidPatternMessage
+	}
+
+	// nothing to subscribe to, e.g. the answer to the question isn't a link
+	hasRepositories := slices.ContainsFunc(strings.Fields(repositoryURLs), config.GithubPattern.MatchString)
+	if len(ownerSubscriptions) == 0 && !hasRepositories {
+		return invalidPatternMessage
+	}
+
+	origin := repo.SubscriptionOrigin{MessageThreadID: messageThreadID, Manual: true}
+
+	_, err := h.repository.AddUserSubscription(ctx, user, repositoryURLs, origin)
 	if err != nil {
 		logs.LogBotErrorMessage(update, err)
 
-		return errorMessage
+		return ErrorMessage
 	}
 
-	var answer strings.Builder
+	for index := range ownerSubscriptions {
+		ownerSubscription := ownerSubscriptions[index]
+
+		if _, err := h.importer.FollowOwner(github.WithInteractive(ctx), &ownerSubscription); err != nil {
+			logs.LogBotErrorMessage(update, err)
 
-	if len(selectedRepository) == 0 {
-		return emptySubscriptions
+			return ErrorMessage
+		}
 	}
 
-	answer.WriteString(subscriptionHeader)
+	return i18n.T(user.Language, successSubscribedMessage) + h.fetchSubscriptions(ctx, update, user, repositoryURLs)
+}
+
+// fetchSubscriptions fetches the first subscribed repositories right away, so the user sees their versions
+// without waiting for the survey, the rest are fetched by the release monitor.
+func (h *SubscriptionsHandler) fetchSubscriptions(
+	ctx context.Context,
+	update *models.Update,
+	user *entities.User,
+	repositoryURLs string,
+) string {
+	var (
+		answer  strings.Builder
+		fetched int
+	)
 
-	for index := range selectedRepository {
-		repository := selectedRepository[index]
+	// fetches use the interactive GitHub API budget, so they aren't queued behind the survey,
+	// when it's spent they're postponed to the survey
+	ctx, cancel := context.WithTimeout(github.WithInteractive(ctx), h.fetchTimeout)
+	defer cancel()
 
-		latestTag := fallbackTag
-		if repository.LatestTag != emptyString {
-			latestTag = repository.LatestTag
+	for _, repositoryURL := range strings.Fields(repositoryURLs) {
+		if fetched == maxImmediateFetches {
+			break
 		}
 
+		if !config.GithubPattern.MatchString(repositoryURL) {
+			continue
+		}
+
+		fetched++
+
 		answer.WriteString(newLineTag)
-		answer.WriteString(latestTag)
-		answer.WriteString(delim)
-		answer.WriteString(repository.URL)
+		answer.WriteString(h.fetchSubscription(ctx, update, user, repositoryURL))
 	}
 
 	return answer.String()
 }
 
-func (h *SubscriptionsHandler) SubscribeHandler(
+func (h *SubscriptionsHandler) fetchSubscription(
 	ctx context.Context,
 	update *models.Update,
 	user *entities.User,
+	repositoryURL string,
 ) string {
-	err := h.repository.AddUserSubscription(ctx, user, update.Message.Text)
+	repository, err := h.repository.GetUserSubscription(ctx, user, repositoryURL)
 	if err != nil {
 		logs.LogBotErrorMessage(update, err)
 
-		return errorMessage
+		// the line is a part of the answer, so it isn't translated by the handler wrapper
+		return i18n.T(user.Language, ErrorMessage)
+	}
+
+	postponed := i18n.Sprintf(user.Language, fetchPostponedLine, repository.URL, repository.ShortName)
+
+	if repository.LastCheckedAt == nil {
+		if err := h.checker.CheckRepository(ctx, repository); err != nil {
+			logs.LogBotErrorMessage(update, err)
+
+			return postponed
+		}
 	}
 
-	return successSubscribedMessage
+	latestTag := i18n.T(user.Language, fallbackTag)
+	if repository.LatestTag != emptyString {
+		latestTag = html.EscapeString(repository.LatestTag)
+	}
+
+	var answer strings.Builder
+
+	answer.WriteString(i18n.Sprintf(user.Language, fetchedRepositoryLine, repository.URL, repository.ShortName, latestTag))
+
+	releases, err := h.checker.GetRecentReleases(ctx, repository, recentReleasesCount)
+	if err != nil {
+		logs.LogBotErrorMessage(update, err)
+
+		return answer.String()
+	}
+
+	for index := range releases {
+		release := releases[index]
+
+		answer.WriteString(newLineTag)
+		answer.WriteString(i18n.Sprintf(user.Language, fetchedReleaseLine, release.URL, html.EscapeString(release.TagName)))
+
+		if release.PublishedAt != nil {
+			answer.WriteString(delim)
+			answer.WriteString(release.PublishedAt.Format(historyDateLayout))
+		}
+	}
+
+	return answer.String()
 }
 
 func (h *SubscriptionsHandler) UnsubscribeHandler(
@@ -91,7 +203,20 @@ func (h *SubscriptionsHandler) UnsubscribeHandler(
 	if err != nil {
 		logs.LogBotErrorMessage(update, err)
 
-		return errorMessage
+		return ErrorMessage
+	}
+
+	for _, ownerURL := range strings.Fields(update.Message.Text) {
+		uriMatches := config.GithubOwnerPattern.FindStringSubmatch(ownerURL)
+		if len(uriMatches) == 0 {
+			continue
+		}
+
+		if err := h.repository.RemoveOwnerSubscription(ctx, user, uriMatches[1]); err != nil {
+			logs.LogBotErrorMessage(update, err)
+
+			return ErrorMessage
+		}
 	}
 
 	return successUnsubscribedMessage
@@ -102,12 +227,79 @@ func (h *SubscriptionsHandler) RemoveAllSubscriptionsHandler(
 	update *models.Update,
 	user *entities.User,
 ) string {
+	args := strings.Fields(update.Message.Text)
+	if len(args) != 2 || !strings.EqualFold(args[1], removeAllConfirmation) { //nolint:mnd // command and confirmation
+		return removeAllNotConfirmed
+	}
+
 	err := h.repository.RemoveAllUserSubscriptions(ctx, user)
 	if err != nil {
 		logs.LogBotErrorMessage(update, err)
 
-		return errorMessage
+		return ErrorMessage
 	}
 
 	return successUnsubscribedMessage
 }
+
+// TopicID returns the forum topic of the message, zero means the chat has no topics.
+func TopicID(message *models.Message) int {
+	// replies in chats without topics have a thread id as well
+	if !message.IsTopicMessage {
+		return 0
+	}
+
+	return message.MessageThreadID
+}
+
+// parseOwnerSubscriptions splits the message into owner subscriptions with their filter options
+// and the rest of the message with repository urls.
+func parseOwnerSubscriptions(
+	receivedMessage string,
+	user *entities.User,
+	messageThreadID int,
+) ([]entities.OwnerSubscription, string, bool) {
+	var (
+		owners         []string
+		repositoryURLs []string
+		filters        entities.OwnerSubscription
+	)
+
+	for _, field := range strings.Fields(receivedMessage) {
+		switch {
+		case strings.HasPrefix(field, includeOption):
+			filters.IncludePattern = strings.TrimPrefix(field, includeOption)
+		case strings.HasPrefix(field, excludeOption):
+			filters.ExcludePattern = strings.TrimPrefix(field, excludeOption)
+		case field == skipArchivedOption:
+			filters.SkipArchived = true
+		case field == skipForksOption:
+			filters.SkipForks = true
+		default:
+			if uriMatches := config.GithubOwnerPattern.FindStringSubmatch(field); len(uriMatches) > 0 {
+				owners = append(owners, uriMatches[1])
+			} else {
+				repositoryURLs = append(repositoryURLs, field)
+			}
+		}
+	}
+
+	for _, pattern := range []string{filters.IncludePattern, filters.ExcludePattern} {
+		if _, err := path.Match(pattern, emptyString); err != nil {
+			return nil, emptyString, false
+		}
+	}
+
+	ownerSubscriptions := make([]entities.OwnerSubscription, 0, len(owners))
+
+	for _, owner := range owners {
+		ownerSubscription := filters
+		ownerSubscription.UserID = user.ID
+		ownerSubscription.Owner = owner
+		ownerSubscription.MessageThreadID = messageThreadID
+		ownerSubscription.User = user
+		ownerSubscriptions = append(ownerSubscriptions, ownerSubscription)
+	}
+
+	return ownerSubscriptions, strings.Join(repositoryURLs, " "), true
+}
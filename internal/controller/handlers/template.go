@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/controller/logs"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/entities"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/i18n"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/templates"
+)
+
+const (
+	templateGlobalMessage string = "Notification template: global"
+	templateCustomMessage string = "Notification template:\n<code>%s</code>"
+	templateHelpMessage   string = "Usage: /template [preset|reset|custom text/template]\nPresets: %s\nVariables: " +
+		"{{.Repo}}, {{.RepoURL}}, {{.OldTag}}, {{.NewTag}}, {{.URL}}, {{.Notes}}, {{.Date}}, " +
+		"{{range .Assets}}{{.Name}} {{.URL}}{{end}}"
+	templateInvalidMessage string = "Invalid template: %s"
+	templateSavedMessage   string = "Notification template is saved, preview:\n\n%s"
+	templateResetOption    string = "reset"
+)
+
+// TemplateHandler views or changes the notification template: a preset, a custom one or the global one.
+func (h *SettingsHandler) TemplateHandler(
+	ctx context.Context,
+	update *models.Update,
+	user *entities.User,
+) string {
+	args := strings.Fields(update.Message.Text)
+	if len(args) == 1 {
+		return formatTemplate(user)
+	}
+
+	// a custom template is the rest of the message after the command as is, line breaks are a part of it
+	command := strings.TrimSpace(update.Message.Text)
+	text := strings.TrimSpace(command[strings.IndexFunc(command, unicode.IsSpace):])
+
+	if len(args) == 2 { //nolint:mnd // command and option
+		if args[1] == templateResetOption {
+			text = emptyString
+		} else if presetText, ok := templates.Preset(args[1]); ok {
+			text = presetText
+		}
+	}
+
+	preview := i18n.T(user.Language, templateGlobalMessage)
+
+	if text != emptyString {
+		var err error
+
+		preview, err = templates.Preview(text)
+		if err != nil {
+			return i18n.Sprintf(user.Language, templateInvalidMessage, html.EscapeString(err.Error()))
+		}
+
+		preview = i18n.Sprintf(user.Language, templateSavedMessage, preview)
+	}
+
+	user.NotificationTemplate = text
+
+	if err := h.repository.UpdateUserSettings(ctx, user, "notification_template"); err != nil {
+		logs.LogBotErrorMessage(update, err)
+
+		return ErrorMessage
+	}
+
+	return preview
+}
+
+func formatTemplate(user *entities.User) string {
+	current := i18n.T(user.Language, templateGlobalMessage)
+	if user.NotificationTemplate != emptyString {
+		current = i18n.Sprintf(user.Language, templateCustomMessage, html.EscapeString(user.NotificationTemplate))
+	}
+
+	help := i18n.Sprintf(user.Language, templateHelpMessage, strings.Join(templates.PresetNames(), ", "))
+
+	return current + newLineTag + newLineTag + html.EscapeString(help)
+}
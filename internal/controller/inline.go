@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/controller/logs"
+)
+
+const (
+	// inlineCacheTime is how long Telegram may show the same results for the same query, in seconds.
+	inlineCacheTime int = 300
+	// inlineRetryCacheTime is used for throttled and failed lookups, zero means the Telegram default.
+	inlineRetryCacheTime int = 1
+)
+
+func (bc *BotController) registerInlineHandler() {
+	bc.bot.RegisterHandlerMatchFunc(
+		func(update *models.Update) bool {
+			return update.InlineQuery != nil
+		},
+		bc.inlineQueryHandler,
+	)
+}
+
+func (bc *BotController) inlineQueryHandler(ctx context.Context, _ *bot.Bot, update *models.Update) {
+	logs.LogBotIncomingMessage(update)
+
+	results, final := bc.inlineHandler.InlineQueryHandler(ctx, update)
+
+	cacheTime := inlineCacheTime
+	if !final {
+		cacheTime = inlineRetryCacheTime
+	}
+
+	_, err := bc.bot.AnswerInlineQuery(ctx, &bot.AnswerInlineQueryParams{
+		InlineQueryID: update.InlineQuery.ID,
+		Results:       results,
+		CacheTime:     cacheTime,
+		// results are translated to the user language
+		IsPersonal: true,
+	})
+	if err != nil {
+		logs.LogBotErrorMessage(update, err)
+	}
+}
@@ -7,31 +7,60 @@ import (
 )
 
 func LogBotIncomingMessage(update *models.Update) {
+	chatID, sender, text := source(update)
+
 	slog.Info(
 		"[BOT] New message",
-		"chatID", update.Message.Chat.ID,
-		"senderID", update.Message.From.ID,
-		"username", update.Message.From.Username,
-		"message", update.Message.Text,
+		"chatID", chatID,
+		"senderID", sender.ID,
+		"username", sender.Username,
+		"message", text,
 	)
 }
 
 func LogBotOutgoingMessage(update *models.Update, answer string) {
+	chatID, sender, _ := source(update)
+
 	slog.Info(
 		"[BOT] Send message",
-		"chatID", update.Message.Chat.ID,
-		"userID", update.Message.From.ID,
-		"username", update.Message.From.Username,
+		"chatID", chatID,
+		"userID", sender.ID,
+		"username", sender.Username,
 		"message", answer,
 	)
 }
 
 func LogBotErrorMessage(update *models.Update, err error) {
+	chatID, sender, _ := source(update)
+
 	slog.Error(
 		"[BOT] User[%d|%d:@%s]: %s",
-		"chatID", update.Message.Chat.ID,
-		"userID", update.Message.From.ID,
-		"username", update.Message.From.Username,
+		"chatID", chatID,
+		"userID", sender.ID,
+		"username", sender.Username,
 		"error", err.Error(),
 	)
 }
+
+// source returns the chat, the sender and the text of the message or the callback query.
+func source(update *models.Update) (int64, *models.User, string) {
+	switch {
+	case update.Message != nil && update.Message.From != nil:
+		return update.Message.Chat.ID, update.Message.From, update.Message.Text
+	case update.Message != nil:
+		return update.Message.Chat.ID, &models.User{}, update.Message.Text
+	case update.CallbackQuery != nil:
+		var chatID int64
+		if message := update.CallbackQuery.Message.Message; message != nil {
+			chatID = message.Chat.ID
+		} else if message := update.CallbackQuery.Message.InaccessibleMessage; message != nil {
+			chatID = message.Chat.ID
+		}
+
+		return chatID, &update.CallbackQuery.From, update.CallbackQuery.Data
+	case update.InlineQuery != nil && update.InlineQuery.From != nil:
+		return 0, update.InlineQuery.From, update.InlineQuery.Query
+	default:
+		return 0, &models.User{}, ""
+	}
+}
@@ -14,6 +14,13 @@ func (bc *BotController) registerDefaultMiddlewares() {
 
 func (bc *BotController) writingActionMiddleware(next bot.HandlerFunc) bot.HandlerFunc {
 	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		// callback queries and other updates without a message are answered without the typing action
+		if update.Message == nil {
+			next(ctx, b, update)
+
+			return
+		}
+
 		_, err := bc.bot.SendChatAction(ctx, &bot.SendChatActionParams{
 			ChatID: update.Message.Chat.ID,
 			Action: models.ChatActionTyping,
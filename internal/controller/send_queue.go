@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"golang.org/x/time/rate"
+)
+
+// Source: https://core.telegram.org/bots/faq#my-bot-is-hitting-limits-how-do-i-avoid-this
+// the API will not allow more than 30 messages per second or so
+// and more than one message per second in a particular chat.
+const (
+	globalMessagesPerSecond = 30
+	chatMessagesPerSecond   = 1
+	maxFloodRetries         = 5
+	// chat limiters idle longer than this are dropped, their bucket is full again anyway
+	chatLimiterIdleTime = time.Minute
+	chatLimitersPruneAt = 10000
+)
+
+type chatLimiter struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+// sendQueue is the single gate for every outgoing message: callers wait in line for the global
+// and per-chat limits, and sends rejected by Telegram with 429 are retried after retry_after.
+type sendQueue struct {
+	global *rate.Limiter
+
+	mu          sync.Mutex
+	chats       map[int64]*chatLimiter
+	pausedUntil time.Time
+}
+
+func newSendQueue() *sendQueue {
+	return &sendQueue{
+		global: rate.NewLimiter(globalMessagesPerSecond, globalMessagesPerSecond),
+		chats:  make(map[int64]*chatLimiter),
+	}
+}
+
+func (q *sendQueue) send(ctx context.Context, chatID int64, send func(ctx context.Context) error) error {
+	for attempt := 0; ; attempt++ {
+		if err := q.wait(ctx, chatID); err != nil {
+			return fmt.Errorf("[BOT] send queue wait failed: %w", err)
+		}
+
+		err := send(ctx)
+
+		var tooManyRequests *bot.TooManyRequestsError
+		if !errors.As(err, &tooManyRequests) || attempt >= maxFloodRetries {
+			return err
+		}
+
+		retryAfter := time.Duration(tooManyRequests.RetryAfter) * time.Second
+		q.pause(retryAfter)
+
+		slog.Warn("[BOT] Flood limit exceeded, retry later", "chatID", chatID, "retryAfter", retryAfter)
+	}
+}
+
+func (q *sendQueue) wait(ctx context.Context, chatID int64) error {
+	if err := q.chatLimiter(chatID).Wait(ctx); err != nil {
+		return err
+	}
+
+	if err := q.global.Wait(ctx); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	pause := time.Until(q.pausedUntil)
+	q.mu.Unlock()
+
+	if pause <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(pause)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// pause stops all sends, since Telegram's retry_after applies to the whole bot.
+func (q *sendQueue) pause(retryAfter time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if pausedUntil := time.Now().Add(retryAfter); pausedUntil.After(q.pausedUntil) {
+		q.pausedUntil = pausedUntil
+	}
+}
+
+func (q *sendQueue) chatLimiter(chatID int64) *rate.Limiter {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+
+	if len(q.chats) >= chatLimitersPruneAt {
+		for id, limiter := range q.chats {
+			if now.Sub(limiter.lastUsed) > chatLimiterIdleTime {
+				delete(q.chats, id)
+			}
+		}
+	}
+
+	limiter, ok := q.chats[chatID]
+	if !ok {
+		limiter = &chatLimiter{limiter: rate.NewLimiter(chatMessagesPerSecond, 1)}
+		q.chats[chatID] = limiter
+	}
+
+	limiter.lastUsed = now
+
+	return limiter.limiter
+}
@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/controller/handlers"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/controller/logs"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/entities"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/i18n"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/repo"
+)
+
+func (bc *BotController) mySubscriptionsHandler(
+	ctx context.Context,
+	update *models.Update,
+	user *entities.User,
+) (string, models.ReplyMarkup) {
+	page := bc.subscriptionHandler.MySubscriptionsHandler(ctx, update, user)
+
+	return page.Text, bc.subscriptionsKeyboard(user.Language, update.Message.Chat.ID, page)
+}
+
+// subscriptionsPageCallback shows another page of the subscription list in place of the current one.
+func (bc *BotController) subscriptionsPageCallback(ctx context.Context, _ *bot.Bot, update *models.Update) {
+	logs.LogBotIncomingMessage(update)
+
+	// the button stays in the loading state until the query is answered
+	defer bc.answerCallbackQuery(ctx, update)
+
+	chatID, messageID, user, ok := bc.callbackUser(ctx, update)
+	if !ok {
+		return
+	}
+
+	payload, _, _ := cutLast(update.CallbackQuery.Data, callbackDataDelimiter)
+
+	query, ok := handlers.ParseSubscriptionsCallback(payload)
+	if !ok {
+		return
+	}
+
+	page := bc.subscriptionHandler.SubscriptionsPage(ctx, update, &user, query)
+	answer := i18n.T(user.Language, page.Text)
+	disableWebPagePreview := true
+
+	err := bc.sendQueue.send(ctx, chatID, func(ctx context.Context) error {
+		_, err := bc.bot.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:             chatID,
+			MessageID:          messageID,
+			Text:               answer,
+			ParseMode:          models.ParseModeHTML,
+			LinkPreviewOptions: &models.LinkPreviewOptions{IsDisabled: &disableWebPagePreview},
+			ReplyMarkup:        bc.subscriptionsKeyboard(user.Language, chatID, page),
+		})
+
+		return err
+	})
+	if err != nil {
+		logs.LogBotErrorMessage(update, err)
+
+		return
+	}
+
+	logs.LogBotOutgoingMessage(update, answer)
+}
+
+// subscriptionsKeyboard returns the page buttons and the sort switch of the subscription list.
+func (bc *BotController) subscriptionsKeyboard(
+	language string,
+	chatID int64,
+	page handlers.SubscriptionsPage,
+) models.ReplyMarkup {
+	if page.Pages == 0 {
+		return nil
+	}
+
+	var row []models.InlineKeyboardButton
+
+	if page.Query.Page > 0 {
+		previous := page.Query
+		previous.Page--
+		row = append(row, models.InlineKeyboardButton{
+			Text:         i18n.T(language, "« Prev"),
+			CallbackData: bc.signCallbackPayload(previous.Payload(), chatID),
+		})
+	}
+
+	resorted := page.Query
+	resorted.Page = 0
+	sortLabel := "Sort by release"
+
+	if resorted.Sort == repo.SortByRelease {
+		resorted.Sort = repo.SortByName
+		sortLabel = "Sort by name"
+	} else {
+		resorted.Sort = repo.SortByRelease
+	}
+
+	row = append(row, models.InlineKeyboardButton{
+		Text:         i18n.T(language, sortLabel),
+		CallbackData: bc.signCallbackPayload(resorted.Payload(), chatID),
+	})
+
+	if page.Query.Page < page.Pages-1 {
+		next := page.Query
+		next.Page++
+		row = append(row, models.InlineKeyboardButton{
+			Text:         i18n.T(language, "Next »"),
+			CallbackData: bc.signCallbackPayload(next.Payload(), chatID),
+		})
+	}
+
+	return &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{row}}
+}
@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-telegram/bot"
+)
+
+const (
+	webhookReadHeaderTimeout time.Duration = 10 * time.Second
+	webhookShutdownTimeout   time.Duration = 10 * time.Second
+)
+
+// StartWebhook receives Telegram updates on the webhook, unlike the long poll it may run on every instance
+// behind a load balancer, since Telegram delivers each update to the webhook url once.
+func (bc *BotController) StartWebhook(ctx context.Context) error {
+	// every instance sets the same webhook, so it doesn't matter which one starts first
+	_, err := bc.bot.SetWebhook(ctx, &bot.SetWebhookParams{URL: bc.webhookURL, SecretToken: bc.webhookSecret})
+	if err != nil {
+		return fmt.Errorf("[BOT] set webhook failed: %w", err)
+	}
+
+	server := &http.Server{
+		Addr:              bc.webhookListen,
+		Handler:           bc.bot.WebhookHandler(),
+		ReadHeaderTimeout: webhookReadHeaderTimeout,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), webhookShutdownTimeout)
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Error("[BOT] Webhook server shutdown failed", "error", err)
+		}
+	}()
+
+	go bc.bot.StartWebhook(ctx)
+
+	slog.Info("[BOT] Starting webhook...", "listen", bc.webhookListen)
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("[BOT] webhook server failed: %w", err)
+	}
+
+	slog.Info("[BOT] Close webhook...")
+
+	return nil
+}
+
+// webhookSecret derives the token Telegram sends with webhook requests from the bot token,
+// so forged updates are rejected.
+func webhookSecret(telegramAPIKey string) string {
+	secret := sha256.Sum256([]byte("webhook:" + telegramAPIKey))
+
+	return hex.EncodeToString(secret[:])
+}
@@ -1,25 +1,217 @@
 package entities
 
 import (
+	"time"
+	"unicode/utf8"
+
 	"gorm.io/gorm"
 )
 
+const (
+	DeliveryModeInstant string = "instant"
+	DeliveryModeDaily   string = "daily"
+	DeliveryModeWeekly  string = "weekly"
+
+	QuietModeHold   string = "hold"
+	QuietModeSilent string = "silent"
+)
+
+// User is a subscriber chat, subscriptions and settings are shared by everyone in the chat.
 type User struct {
 	gorm.Model
-	ExternalID int64 `gorm:"not null"`
+	// ExternalID is the Telegram chat id, it's equal to the user id in private chats
+	ExternalID int64 `gorm:"not null;uniqueIndex"`
+	// ChatType is the Telegram chat type: private, group, supergroup or channel
+	ChatType      string `gorm:"size:20"`
+	DeliveryMode  string `gorm:"size:10;not null;default:instant"`
+	DigestHour    int    `gorm:"not null;default:9"`
+	DigestWeekday int    `gorm:"not null;default:1"`
+	LastDigestAt  *time.Time
+	TimeZone      string `gorm:"size:50;not null;default:UTC"`
+	// QuietStart and QuietEnd are minutes since local midnight, equal values disable quiet hours
+	QuietStart int    `gorm:"not null;default:0"`
+	QuietEnd   int    `gorm:"not null;default:0"`
+	QuietMode  string `gorm:"size:10;not null;default:hold"`
+	// Language of the bot messages, it's detected from the Telegram client on the first message
+	Language string `gorm:"size:10"`
+	// NotificationTemplate is the user text/template of release notifications, empty means the global one
+	NotificationTemplate string `gorm:"size:4000"`
+	// LastManualCheckAt is the time of the last /check command, used for the cooldown
+	LastManualCheckAt *time.Time
+}
+
+// MinutesPerHour converts quiet hours, they're stored as minutes since midnight.
+const MinutesPerHour = 60
+
+// MaxMessageLength is the Telegram limit of a message text.
+// Source: https://core.telegram.org/bots/api#sendmessage
+const MaxMessageLength int = 4096
+
+// maxErrorLength is the size of the last error columns.
+const maxErrorLength = 255
+
+// TruncateError shortens the error message to fit the last error columns without splitting a character.
+func TruncateError(err error) string {
+	message := err.Error()
+	if len(message) <= maxErrorLength {
+		return message
+	}
+
+	cut := maxErrorLength
+	for cut > 0 && !utf8.RuneStart(message[cut]) {
+		cut--
+	}
+
+	return message[:cut]
+}
+
+// Location returns the user time zone, unknown zones fall back to UTC.
+func (u *User) Location() *time.Location {
+	location, err := time.LoadLocation(u.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+
+	return location
+}
+
+// QuietUntil reports whether now is within the user quiet hours and when they end.
+func (u *User) QuietUntil(now time.Time) (time.Time, bool) {
+	if u.QuietStart == u.QuietEnd {
+		return time.Time{}, false
+	}
+
+	local := now.In(u.Location())
+	minutes := local.Hour()*MinutesPerHour + local.Minute()
+
+	quiet := u.QuietStart <= minutes && minutes < u.QuietEnd
+	if u.QuietStart > u.QuietEnd {
+		// the window crosses midnight, e.g. 22:00-08:00
+		quiet = minutes >= u.QuietStart || minutes < u.QuietEnd
+	}
+
+	if !quiet {
+		return time.Time{}, false
+	}
+
+	end := time.Date(local.Year(), local.Month(), local.Day(), 0, u.QuietEnd, 0, 0, local.Location())
+	if !end.After(local) {
+		end = end.AddDate(0, 0, 1)
+	}
+
+	return end, true
 }
 
 type Repository struct {
 	gorm.Model
-	LatestTag string `gorm:"size:50"`
-	ShortName string `gorm:"size:50;not null;unique"`
-	URL       string `gorm:"size:100;not null;unique"`
+	LatestTag       string    `gorm:"size:50"`
+	ShortName       string    `gorm:"size:50;not null;unique"`
+	URL             string    `gorm:"size:100;not null;unique"`
+	NextCheckAt     time.Time `gorm:"index"`
+	LastCheckedAt   *time.Time
+	LastReleaseAt   *time.Time
+	ReleaseInterval time.Duration
+	// OrphanedAt is the time when the last subscriber was noticed to be gone
+	OrphanedAt          *time.Time
+	LastSuccessAt       *time.Time
+	LastDetectedAt      *time.Time
+	LastError           string `gorm:"size:255"`
+	ConsecutiveFailures int    `gorm:"not null;default:0"`
+}
+
+// Release is a version of the repository detected by the monitor.
+type Release struct {
+	gorm.Model
+	RepositoryID uint   `gorm:"not null;uniqueIndex:idx_release_repository_tag"`
+	TagName      string `gorm:"size:50;not null;uniqueIndex:idx_release_repository_tag"`
+	URL          string `gorm:"size:200"`
+	PublishedAt  *time.Time
+	DetectedAt   time.Time   `gorm:"index"`
+	Repository   *Repository `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 }
 
 type UserRepository struct {
 	gorm.Model
 	UserID       uint
 	RepositoryID uint
-	User         *User       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
-	Repository   *Repository `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	// MutedUntil suspends notifications about the repository releases
+	MutedUntil *time.Time
+	// MessageThreadID is the forum topic the subscription was made in, zero is the chat itself
+	MessageThreadID int `gorm:"not null;default:0"`
+	// OwnerSubscriptionID is the owner subscription which made the subscription, nil for the ones made by hand
+	OwnerSubscriptionID *uint       `gorm:"index"`
+	User                *User       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Repository          *Repository `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+}
+
+type StarredImport struct {
+	gorm.Model
+	UserID         uint   `gorm:"not null;uniqueIndex:idx_starred_import_user_username"`
+	GithubUsername string `gorm:"size:50;not null;uniqueIndex:idx_starred_import_user_username"`
+	// MessageThreadID is the forum topic of the imported subscriptions
+	MessageThreadID int   `gorm:"not null;default:0"`
+	User            *User `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+type OwnerSubscription struct {
+	gorm.Model
+	UserID         uint   `gorm:"not null;uniqueIndex:idx_owner_subscription_user_owner"`
+	Owner          string `gorm:"size:50;not null;uniqueIndex:idx_owner_subscription_user_owner"`
+	IncludePattern string `gorm:"size:100"`
+	ExcludePattern string `gorm:"size:100"`
+	SkipArchived   bool
+	SkipForks      bool
+	// MessageThreadID is the forum topic of the owner repository subscriptions
+	MessageThreadID int   `gorm:"not null;default:0"`
+	User            *User `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// Lease is a database lock which allows only one bot instance to run leader-only jobs.
+type Lease struct {
+	gorm.Model
+	Name      string `gorm:"size:50;not null;unique"`
+	Holder    string `gorm:"size:100;not null"`
+	ExpiresAt time.Time
+}
+
+const (
+	NotificationStatusPending string = "pending"
+	NotificationStatusSent    string = "sent"
+	NotificationStatusFailed  string = "failed"
+)
+
+// Notification is an outbox record of a single message delivery to a single recipient.
+type Notification struct {
+	gorm.Model
+	// DedupKey prevents enqueueing the same message for the same recipient twice
+	DedupKey     string `gorm:"size:150;not null;unique"`
+	UserID       uint   `gorm:"not null;index"`
+	RepositoryID uint
+	ChatID       int64 `gorm:"not null"`
+	// MessageThreadID is the forum topic the notification is posted to
+	MessageThreadID int       `gorm:"not null;default:0"`
+	Text            string    `gorm:"not null"`
+	ReleaseURL      string    `gorm:"size:200"`
+	Status          string    `gorm:"size:20;not null;index"`
+	Attempts        int       `gorm:"not null;default:0"`
+	NextAttemptAt   time.Time `gorm:"index"`
+	LastError       string    `gorm:"size:255"`
+	SentAt          *time.Time
+	User            *User `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// DigestItem is a detected release queued for the user's next digest.
+type DigestItem struct {
+	gorm.Model
+	DedupKey      string `gorm:"size:150;not null;unique"`
+	UserID        uint   `gorm:"not null;index"`
+	RepositoryID  uint
+	ShortName     string `gorm:"size:50;not null"`
+	RepositoryURL string `gorm:"size:100;not null"`
+	OldTag        string `gorm:"size:50"`
+	NewTag        string `gorm:"size:50;not null"`
+	ReleaseURL    string `gorm:"size:200"`
+	// MessageThreadID is the forum topic the digest with the release is posted to
+	MessageThreadID int   `gorm:"not null;default:0"`
+	User            *User `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 }
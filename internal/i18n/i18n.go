@@ -0,0 +1,56 @@
+package i18n
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	English string = "en"
+	Russian string = "ru"
+
+	DefaultLanguage = English
+)
+
+// catalogs maps the language to its translations of English messages, it's built once on the first use.
+var catalogs = sync.OnceValue(func() map[string]map[string]string { //nolint:gochecknoglobals // immutable catalogs
+	return map[string]map[string]string{
+		Russian: russian(),
+	}
+})
+
+// Languages returns the supported languages.
+func Languages() []string {
+	return []string{English, Russian}
+}
+
+// IsSupported reports whether the language has a message catalog.
+func IsSupported(language string) bool {
+	return slices.Contains(Languages(), language)
+}
+
+// Detect picks the supported language for the Telegram language_code (an IETF tag like "ru" or "ru-RU").
+func Detect(languageCode string) string {
+	language, _, _ := strings.Cut(strings.ToLower(languageCode), "-")
+	if IsSupported(language) {
+		return language
+	}
+
+	return DefaultLanguage
+}
+
+// T translates the English message, messages without a translation stay in English.
+func T(language string, message string) string {
+	if translation, ok := catalogs()[language][message]; ok {
+		return translation
+	}
+
+	return message
+}
+
+// Sprintf translates the English format and formats it.
+func Sprintf(language string, format string, args ...any) string {
+	return fmt.Sprintf(T(language, format), args...)
+}
@@ -0,0 +1,174 @@
+package i18n
+
+// russian is the Russian catalog, keys are English messages as they are used in the code.
+func russian() map[string]string {
+	return map[string]string{
+		// common
+		"Some error caused, please try latter :(": "Произошла ошибка, попробуйте позже :(",
+		"Say /help":         "Напишите /help",
+		"Cancelled":         "Отменено",
+		"Nothing to cancel": "Нечего отменять",
+		"The %s command timed out, please send it again": "Время ожидания ответа на команду %s истекло, " +
+			"отправьте её ещё раз",
+		"Send a new value or /cancel": "Отправьте новое значение или /cancel",
+		"Send me GitHub repository or owner links or /cancel": "Отправьте ссылки на GitHub-репозитории или " +
+			"владельцев или /cancel",
+		"Send me GitHub repository or owner links to unsubscribe from or /cancel": "Отправьте ссылки на " +
+			"GitHub-репозитории или владельцев, от которых нужно отписаться, или /cancel",
+		"Send yes to remove all subscriptions or /cancel": "Отправьте yes, чтобы удалить все подписки, или /cancel",
+		"Send me a GitHub username or /cancel":            "Отправьте имя пользователя GitHub или /cancel",
+		"Nothing removed, send /remove_all_subscriptions yes to confirm": "Ничего не удалено, отправьте " +
+			"/remove_all_subscriptions yes для подтверждения",
+		"Language: %s":             "Язык: %s",
+		"Usage: /language [en|ru]": "Использование: /language [en|ru]",
+
+		// subscriptions
+		"Subscriptions: empty":          "Подписки: нет",
+		"Subscriptions %d-%d of %d:":    "Подписки %d-%d из %d:",
+		"No subscriptions match \"%s\"": "Нет подписок, подходящих под «%s»",
+		"Usage: /my_subscriptions [--sort=name|release] [filter]": "Использование: /my_subscriptions " +
+			"[--sort=name|release] [фильтр]",
+		"fetch in progress":                           "загружается",
+		"Successfully subscribed!":                    "Подписка оформлена!",
+		"Successfully unsubscribed!":                  "Подписка отменена!",
+		"Invalid repository name pattern :(":          "Неверный шаблон имени репозитория :(",
+		"<a href=\"%s\">%s</a>: will be fetched soon": "<a href=\"%s\">%s</a>: скоро будет загружен",
+
+		// stars and manifests
+		"Usage: /import_stars [github username] [sync|stop]": "Использование: /import_stars [имя пользователя GitHub] [sync|stop]",
+		"Imported %d starred repositories!":                  "Импортировано отмеченных репозиториев: %d!",
+		"New stars of %s will be subscribed automatically.":  "Новые звёзды %s будут добавляться в подписки автоматически.",
+		"Stars sync for %s is stopped.":                      "Синхронизация звёзд %s остановлена.",
+		"Manifest file is too large :(":                      "Файл манифеста слишком большой :(",
+		"Supported manifests: go.mod, package.json, requirements.txt, Cargo.toml, pom.xml": "Поддерживаемые манифесты: " +
+			"go.mod, package.json, requirements.txt, Cargo.toml, pom.xml",
+		"Manifest has too many dependencies :(":               "В манифесте слишком много зависимостей :(",
+		"Subscribed to %d repositories from %d dependencies!": "Оформлено подписок: %d из %d зависимостей!",
+		"Not found on GitHub: ":                               "Не найдены на GitHub: ",
+		" and %d more":                                        " и ещё %d",
+		"Looking for %d dependencies on GitHub, the result will follow in a reply...": "Ищу на GitHub зависимостей: %d, " +
+			"результат придёт ответом...",
+
+		// settings
+		"Usage: /delivery [instant|daily|weekly] [hour 0-23] [weekday mon-sun]": "Использование: " +
+			"/delivery [instant|daily|weekly] [час 0-23] [день недели mon-sun]",
+		"Delivery mode: instant":                                "Режим доставки: сразу",
+		"Delivery mode: daily digest at %02d:00 %s":             "Режим доставки: ежедневная сводка в %02d:00 %s",
+		"Delivery mode: weekly digest on %s at %02d:00 %s":      "Режим доставки: еженедельная сводка, %s в %02d:00 %s",
+		"Usage: /timezone [IANA time zone, e.g. Europe/Berlin]": "Использование: /timezone [часовой пояс IANA, например Europe/Moscow]",
+		"Time zone: %s": "Часовой пояс: %s",
+		"Usage: /quiet [HH:MM-HH:MM|off] [hold|silent]": "Использование: /quiet [ЧЧ:ММ-ЧЧ:ММ|off] [hold|silent]",
+		"Quiet hours: off": "Тихие часы: выключены",
+		"Quiet hours: %s-%s %s, notifications are held until the end": "Тихие часы: %s-%s %s, " +
+			"уведомления откладываются до их окончания",
+		"Quiet hours: %s-%s %s, notifications are sent silently": "Тихие часы: %s-%s %s, уведомления приходят без звука",
+		"Sunday":    "воскресенье",
+		"Monday":    "понедельник",
+		"Tuesday":   "вторник",
+		"Wednesday": "среда",
+		"Thursday":  "четверг",
+		"Friday":    "пятница",
+		"Saturday":  "суббота",
+
+		// templates
+		"Notification template: global":                  "Шаблон уведомлений: общий",
+		"Notification template:\n<code>%s</code>":        "Шаблон уведомлений:\n<code>%s</code>",
+		"Invalid template: %s":                           "Неверный шаблон: %s",
+		"Notification template is saved, preview:\n\n%s": "Шаблон уведомлений сохранён, пример:\n\n%s",
+		"Usage: /template [preset|reset|custom text/template]\nPresets: %s\nVariables: " +
+			"{{.Repo}}, {{.RepoURL}}, {{.OldTag}}, {{.NewTag}}, {{.URL}}, {{.Notes}}, {{.Date}}, " +
+			"{{range .Assets}}{{.Name}} {{.URL}}{{end}}": "Использование: /template [шаблон|reset|свой text/template]\n" +
+			"Готовые шаблоны: %s\nПеременные: " +
+			"{{.Repo}}, {{.RepoURL}}, {{.OldTag}}, {{.NewTag}}, {{.URL}}, {{.Notes}}, {{.Date}}, " +
+			"{{range .Assets}}{{.Name}} {{.URL}}{{end}}",
+
+		// checks, history and status
+		"Usage: /check [github repository url]": "Использование: /check [ссылка на репозиторий GitHub]",
+		"You are not subscribed to this repository, use /subscribe first": "Вы не подписаны на этот репозиторий, " +
+			"сначала используйте /subscribe",
+		"Cannot check the repository now, see /status for details": "Сейчас не удаётся проверить репозиторий, " +
+			"подробности в /status",
+		"Please wait %s before the next check":                "Следующая проверка будет доступна через %s",
+		"Current version of <a href=\"%s\">%s</a>: %s":        "Текущая версия <a href=\"%s\">%s</a>: %s",
+		"New release detected, notifications are on the way!": "Обнаружен новый релиз, уведомления уже в пути!",
+		"Usage: /history [github repository url] [count, up to 50]": "Использование: " +
+			"/history [ссылка на репозиторий GitHub] [количество, до 50]",
+		"No releases recorded yet":           "Релизов пока нет",
+		"Releases of <a href=\"%s\">%s</a>:": "Релизы <a href=\"%s\">%s</a>:",
+		" (%s after the previous)":           " (через %s после предыдущего)",
+		"Subscriptions health: %d ok, %d failing, %d waiting for the first check": "Состояние подписок: " +
+			"%d в порядке, %d с ошибками, %d ждут первой проверки",
+		"⚠️ <a href=\"%s\">%s</a> - %d failed checks, last success: %s, last release detected: %s\n<code>%s</code>": "⚠️ " +
+			"<a href=\"%s\">%s</a> - неудачных проверок: %d, последний успех: %s, последний релиз найден: %s\n<code>%s</code>",
+		"✅ <a href=\"%s\">%s</a> - last release detected: %s": "✅ <a href=\"%s\">%s</a> - " +
+			"последний релиз найден: %s",
+		"⏳ <a href=\"%s\">%s</a> - waiting for the first check": "⏳ <a href=\"%s\">%s</a> - ждёт первой проверки",
+		"This command is available for admins only":             "Эта команда доступна только администраторам",
+		"No failing repositories :)":                            "Репозиториев с ошибками нет :)",
+		"The most failing repositories:":                        "Репозитории с наибольшим числом ошибок:",
+		"%d. <a href=\"%s\">%s</a> - %d failed checks, last success: %s, last check: %s": "%d. <a href=\"%s\">%s</a> - " +
+			"неудачных проверок: %d, последний успех: %s, последняя проверка: %s",
+		"\n… and %d more": "\n… и ещё %d",
+		"never":           "никогда",
+		"%s ago":          "%s назад",
+		"%dd":             "%d д",
+		"%dh":             "%d ч",
+		"%dm":             "%d мин",
+
+		// release notifications
+		"Release notes":   "Описание релиза",
+		"Show history":    "История релизов",
+		"Mute for 7 days": "Не беспокоить 7 дней",
+		"Unsubscribe":     "Отписаться",
+		"Only chat admins can change subscriptions and settings of this chat": "Менять подписки и настройки " +
+			"этого чата могут только его администраторы",
+		"Latest release of <a href=\"%s\">%s</a>: <a href=\"%s\">%s</a>": "Последний релиз " +
+			"<a href=\"%s\">%s</a>: <a href=\"%s\">%s</a>",
+		"Published %s": "Опубликован %s",
+		"Latest tag, the repository has no releases": "Последний тег, у репозитория нет релизов",
+		"« Prev":          "« Назад",
+		"Next »":          "Вперёд »",
+		"Sort by name":    "По имени",
+		"Sort by release": "По релизам",
+		"Notifications about <a href=\"%s\">%s</a> are muted until %s": "Уведомления о <a href=\"%s\">%s</a> " +
+			"отключены до %s",
+		"You are not subscribed to <a href=\"%s\">%s</a>":       "Вы не подписаны на <a href=\"%s\">%s</a>",
+		"Successfully unsubscribed from <a href=\"%s\">%s</a>!": "Подписка на <a href=\"%s\">%s</a> отменена!",
+		"The repository isn't tracked anymore":                  "Репозиторий больше не отслеживается",
+		"<b>Release digest</b>":                                 "<b>Сводка релизов</b>",
+
+		// commands
+		"base command for user registration": "регистрация пользователя",
+		"view all commands":                  "список команд",
+		"[--sort=name|release] [filter] view your subscriptions page by page": "[--sort=name|release] [фильтр] " +
+			"список подписок по страницам",
+		"[github repository or owner urls] [--include=glob] [--exclude=glob] [--skip-archived] [--skip-forks] " +
+			"subscribe to the new GitHub repository or to all repositories of the owner": "[ссылки на репозитории " +
+			"или владельцев GitHub] [--include=glob] [--exclude=glob] [--skip-archived] [--skip-forks] " +
+			"подписаться на репозиторий GitHub или на все репозитории владельца",
+		"[github repository or owner urls] unsubscribe from the GitHub repository or owner": "[ссылки на репозитории " +
+			"или владельцев GitHub] отписаться от репозитория или владельца",
+		"[yes] remove all exists subscriptions after the confirmation": "[yes] удалить все подписки " +
+			"после подтверждения",
+		"cancel the command waiting for your answer": "отменить команду, которая ждёт вашего ответа",
+		"[github username] [sync|stop] subscribe to all repositories starred by the GitHub user": "[имя пользователя " +
+			"GitHub] [sync|stop] подписаться на все репозитории, отмеченные звездой пользователем GitHub",
+		"subscribe to all dependencies from the uploaded manifest": "подписаться на все зависимости из загруженного " +
+			"манифеста",
+		"[instant|daily|weekly] [hour] [weekday] view or change the notification delivery mode": "[instant|daily|weekly] " +
+			"[час] [день недели] показать или изменить режим доставки уведомлений",
+		"[IANA time zone] view or change your time zone": "[часовой пояс IANA] показать или изменить часовой пояс",
+		"[HH:MM-HH:MM|off] [hold|silent] view or change quiet hours": "[ЧЧ:ММ-ЧЧ:ММ|off] [hold|silent] показать или " +
+			"изменить тихие часы",
+		"[preset|reset|custom text/template] view or change the notification template": "[шаблон|reset|свой " +
+			"text/template] показать или изменить шаблон уведомлений",
+		"[en|ru] view or change the bot language": "[en|ru] показать или изменить язык бота",
+		"[github repository url] check the repository for a new release right away": "[ссылка на репозиторий " +
+			"GitHub] проверить репозиторий на новый релиз прямо сейчас",
+		"[github repository url] [count] view the last releases of the repository": "[ссылка на репозиторий " +
+			"GitHub] [количество] показать последние релизы репозитория",
+		"view the health of your subscriptions": "показать состояние подписок",
+		"view the most failing repositories (admins only)": "показать репозитории с наибольшим числом ошибок " +
+			"(только для администраторов)",
+	}
+}
@@ -0,0 +1,126 @@
+package i18n
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var (
+	htmlTagPattern    = regexp.MustCompile(`<[^>]*>`)
+	formatVerbPattern = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+	letterPattern     = regexp.MustCompile(`[a-zA-Z]`)
+	keywordPattern    = regexp.MustCompile(`^[a-z_:-]+$`)
+)
+
+// messageSources are the packages whose string constants are shown to users.
+func messageSources() []string {
+	return []string{"../controller", "../controller/handlers", "../notifier"}
+}
+
+// isMessage tells user-visible messages apart from command options, keywords, urls, layouts and markup.
+func isMessage(text string) bool {
+	text = strings.TrimSpace(text)
+
+	switch {
+	case strings.HasPrefix(text, "-"), strings.Contains(text, "://"), strings.Contains(text, "2006"):
+		return false
+	case keywordPattern.MatchString(text):
+		return false
+	}
+
+	return letterPattern.MatchString(formatVerbPattern.ReplaceAllString(htmlTagPattern.ReplaceAllString(text, " "), " "))
+}
+
+// stringLiterals returns the values of string constants and the descriptions of registered commands.
+func stringLiterals(node ast.Node) []ast.Expr {
+	switch node := node.(type) {
+	case *ast.GenDecl:
+		if node.Tok != token.CONST {
+			return nil
+		}
+
+		var values []ast.Expr
+
+		for _, spec := range node.Specs {
+			if valueSpec, ok := spec.(*ast.ValueSpec); ok {
+				values = append(values, valueSpec.Values...)
+			}
+		}
+
+		return values
+	case *ast.CallExpr:
+		// the command description is the second argument of the handler registration
+		selector, ok := node.Fun.(*ast.SelectorExpr)
+		if ok && strings.HasPrefix(selector.Sel.Name, "register") && len(node.Args) > 1 {
+			return node.Args[1:2]
+		}
+	}
+
+	return nil
+}
+
+// sourceMessages maps the messages of the sources to their positions.
+func sourceMessages(t *testing.T) map[string]string {
+	t.Helper()
+
+	messages := make(map[string]string)
+	fileSet := token.NewFileSet()
+
+	for _, dir := range messageSources() {
+		fileNames, err := filepath.Glob(filepath.Join(dir, "*.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, fileName := range fileNames {
+			if strings.HasSuffix(fileName, "_test.go") {
+				continue
+			}
+
+			file, err := parser.ParseFile(fileSet, fileName, nil, parser.SkipObjectResolution)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ast.Inspect(file, func(node ast.Node) bool {
+				for _, expr := range stringLiterals(node) {
+					literal, ok := expr.(*ast.BasicLit)
+					if !ok || literal.Kind != token.STRING {
+						continue
+					}
+
+					if text, err := strconv.Unquote(literal.Value); err == nil && isMessage(text) {
+						messages[text] = fileSet.Position(literal.Pos()).String()
+					}
+				}
+
+				return true
+			})
+		}
+	}
+
+	return messages
+}
+
+func TestRussianCatalogCoversMessages(t *testing.T) {
+	t.Parallel()
+
+	messages := sourceMessages(t)
+	if len(messages) == 0 {
+		t.Fatal("no messages found, check the message sources")
+	}
+
+	catalog := russian()
+
+	for message, position := range messages {
+		if _, ok := catalog[message]; !ok {
+			t.Errorf("%s: no Russian translation for %q", position, message)
+		}
+	}
+}
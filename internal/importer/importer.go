@@ -0,0 +1,341 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/config"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/entities"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/manifest"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/monitor/github"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/repo"
+)
+
+const (
+	// maxManifestDependencies limits registry lookups caused by a single uploaded manifest.
+	maxManifestDependencies int = 300
+	// maxConcurrentResolves bounds the registry requests in flight for a single manifest.
+	maxConcurrentResolves int = 8
+)
+
+var ErrTooManyDependencies = errors.New("too many dependencies")
+
+type Importer struct {
+	repository       *repo.Repository
+	githubClient     *github.Client
+	manifestResolver *manifest.Resolver
+}
+
+type ManifestImportResult struct {
+	Dependencies int
+	Subscribed   int
+	Unresolved   []string
+}
+
+func NewImporter(repository *repo.Repository, githubClient *github.Client) *Importer {
+	return &Importer{
+		repository:       repository,
+		githubClient:     githubClient,
+		manifestResolver: manifest.NewResolver(),
+	}
+}
+
+// ImportStarredRepositories subscribes user to every repository starred by the GitHub user
+// and returns the number of new subscriptions.
+func (i *Importer) ImportStarredRepositories(
+	ctx context.Context,
+	user *entities.User,
+	githubUsername string,
+	messageThreadID int,
+) (int, error) {
+	repositories, err := i.githubClient.GetStarredRepositories(ctx, githubUsername)
+	if err != nil {
+		return 0, fmt.Errorf("[IMPORTER] cannot get starred repositories: %w", err)
+	}
+
+	repositoryURLs := make([]string, 0, len(repositories))
+
+	for index := range repositories {
+		repositoryURL := repositories[index].HTMLURL
+		if config.GithubPattern.MatchString(repositoryURL) {
+			repositoryURLs = append(repositoryURLs, repositoryURL)
+		}
+	}
+
+	created, err := i.repository.AddUserSubscription(
+		ctx,
+		user,
+		strings.Join(repositoryURLs, " "),
+		repo.SubscriptionOrigin{MessageThreadID: messageThreadID},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("[IMPORTER] cannot subscribe to starred repositories: %w", err)
+	}
+
+	slog.Info(
+		"[IMPORTER] Starred repositories imported",
+		"user", user.ID,
+		"githubUsername", githubUsername,
+		"count", len(repositoryURLs),
+		"created", created,
+	)
+
+	return created, nil
+}
+
+// Sync refreshes all automatically followed sources: starred repositories and owner subscriptions.
+func (i *Importer) Sync(ctx context.Context) {
+	i.SyncStarredRepositories(ctx)
+	i.SyncOwnerSubscriptions(ctx)
+}
+
+// SyncStarredRepositories re-imports stars for every user who enabled stars sync,
+// so newly starred repositories become subscriptions automatically.
+func (i *Importer) SyncStarredRepositories(ctx context.Context) {
+	starredImports, err := i.repository.GetAllStarredImports(ctx)
+	if err != nil {
+		slog.Error("[IMPORTER] Starred imports selection unexpected error", "error", err)
+
+		return
+	}
+
+	for index := range starredImports {
+		starredImport := starredImports[index]
+		if starredImport.User == nil {
+			continue
+		}
+
+		_, err := i.ImportStarredRepositories(
+			ctx,
+			starredImport.User,
+			starredImport.GithubUsername,
+			starredImport.MessageThreadID,
+		)
+		if err != nil {
+			slog.Error(
+				"[IMPORTER] Stars sync error",
+				"user", starredImport.UserID,
+				"githubUsername", starredImport.GithubUsername,
+				"error", err,
+			)
+		}
+	}
+}
+
+// ParseManifest returns the dependencies declared in the manifest file.
+func (i *Importer) ParseManifest(fileName string, content []byte) ([]manifest.Dependency, error) {
+	dependencies, err := manifest.Parse(fileName, content)
+	if err != nil {
+		return nil, fmt.Errorf("[IMPORTER] cannot parse manifest: %w", err)
+	}
+
+	if len(dependencies) > maxManifestDependencies {
+		return nil, fmt.Errorf("[IMPORTER] %w: %d", ErrTooManyDependencies, len(dependencies))
+	}
+
+	return dependencies, nil
+}
+
+// ImportManifest subscribes user to the GitHub repositories of the manifest dependencies.
+func (i *Importer) ImportManifest(
+	ctx context.Context,
+	user *entities.User,
+	dependencies []manifest.Dependency,
+) (ManifestImportResult, error) {
+	result := ManifestImportResult{Dependencies: len(dependencies)}
+	repositoryURLs := make([]string, 0, len(dependencies))
+	seenURLs := make(map[string]struct{}, len(dependencies))
+
+	for index, repositoryURL := range i.resolveDependencies(ctx, dependencies) {
+		if repositoryURL == "" || !config.GithubPattern.MatchString(repositoryURL) {
+			result.Unresolved = append(result.Unresolved, dependencies[index].Name)
+
+			continue
+		}
+
+		if _, ok := seenURLs[repositoryURL]; !ok {
+			seenURLs[repositoryURL] = struct{}{}
+			repositoryURLs = append(repositoryURLs, repositoryURL)
+		}
+	}
+
+	// manifests are uploaded in private chats, which have no topics
+	created, err := i.repository.AddUserSubscription(
+		ctx, user, strings.Join(repositoryURLs, " "), repo.SubscriptionOrigin{},
+	)
+	if err != nil {
+		return ManifestImportResult{}, fmt.Errorf("[IMPORTER] cannot subscribe to dependencies: %w", err)
+	}
+
+	// existing subscriptions aren't counted, so uploading the same manifest again reports nothing new
+	result.Subscribed = created
+
+	slog.Info(
+		"[IMPORTER] Manifest imported",
+		"user", user.ID,
+		"dependencies", result.Dependencies,
+		"repositories", len(repositoryURLs),
+		"created", created,
+	)
+
+	return result, nil
+}
+
+// resolveDependencies returns the repository url of every dependency in the same order,
+// unresolved ones are empty.
+func (i *Importer) resolveDependencies(ctx context.Context, dependencies []manifest.Dependency) []string {
+	repositoryURLs := make([]string, len(dependencies))
+
+	// errors are logged per dependency, so a wait group with a semaphore is enough
+	var resolvers sync.WaitGroup
+
+	semaphore := make(chan struct{}, maxConcurrentResolves)
+
+	for index := range dependencies {
+		semaphore <- struct{}{}
+
+		resolvers.Go(func() {
+			defer func() { <-semaphore }()
+
+			repositoryURL, err := i.manifestResolver.Resolve(ctx, dependencies[index])
+			if err != nil {
+				slog.Warn("[IMPORTER] Dependency resolving failed", "dependency", dependencies[index].Name, "error", err)
+			}
+
+			repositoryURLs[index] = repositoryURL
+		})
+	}
+
+	resolvers.Wait()
+
+	return repositoryURLs
+}
+
+// FollowOwner saves the owner subscription and subscribes user to the matching owner repositories,
+// the owner repositories are listed first, so unknown owners aren't followed.
+func (i *Importer) FollowOwner(
+	ctx context.Context,
+	ownerSubscription *entities.OwnerSubscription,
+) (int, error) {
+	repositories, err := i.githubClient.GetOwnerRepositories(ctx, ownerSubscription.Owner)
+	if err != nil {
+		return 0, fmt.Errorf("[IMPORTER] cannot get owner repositories: %w", err)
+	}
+
+	if err := i.repository.AddOwnerSubscription(ctx, ownerSubscription); err != nil {
+		return 0, fmt.Errorf("[IMPORTER] cannot save owner subscription: %w", err)
+	}
+
+	return i.subscribeOwnerRepositories(ctx, ownerSubscription, repositories)
+}
+
+// SubscribeOwner subscribes user to every owner repository matching the subscription filters
+// and returns the number of new subscriptions.
+func (i *Importer) SubscribeOwner(
+	ctx context.Context,
+	ownerSubscription *entities.OwnerSubscription,
+) (int, error) {
+	repositories, err := i.githubClient.GetOwnerRepositories(ctx, ownerSubscription.Owner)
+	if err != nil {
+		return 0, fmt.Errorf("[IMPORTER] cannot get owner repositories: %w", err)
+	}
+
+	return i.subscribeOwnerRepositories(ctx, ownerSubscription, repositories)
+}
+
+func (i *Importer) subscribeOwnerRepositories(
+	ctx context.Context,
+	ownerSubscription *entities.OwnerSubscription,
+	repositories []github.RepositoryInfo,
+) (int, error) {
+	repositoryURLs := make([]string, 0, len(repositories))
+
+	for index := range repositories {
+		repository := repositories[index]
+		if !matchOwnerSubscription(ownerSubscription, &repository) {
+			continue
+		}
+
+		if config.GithubPattern.MatchString(repository.HTMLURL) {
+			repositoryURLs = append(repositoryURLs, repository.HTMLURL)
+		}
+	}
+
+	created, err := i.repository.AddUserSubscription(
+		ctx,
+		ownerSubscription.User,
+		strings.Join(repositoryURLs, " "),
+		repo.SubscriptionOrigin{
+			MessageThreadID:     ownerSubscription.MessageThreadID,
+			OwnerSubscriptionID: &ownerSubscription.ID,
+		},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("[IMPORTER] cannot subscribe to owner repositories: %w", err)
+	}
+
+	slog.Info(
+		"[IMPORTER] Owner repositories imported",
+		"user", ownerSubscription.UserID,
+		"owner", ownerSubscription.Owner,
+		"count", len(repositoryURLs),
+		"created", created,
+	)
+
+	return created, nil
+}
+
+// SyncOwnerSubscriptions discovers new repositories of every followed owner.
+func (i *Importer) SyncOwnerSubscriptions(ctx context.Context) {
+	ownerSubscriptions, err := i.repository.GetAllOwnerSubscriptions(ctx)
+	if err != nil {
+		slog.Error("[IMPORTER] Owner subscriptions selection unexpected error", "error", err)
+
+		return
+	}
+
+	for index := range ownerSubscriptions {
+		ownerSubscription := ownerSubscriptions[index]
+		if ownerSubscription.User == nil {
+			continue
+		}
+
+		if _, err := i.SubscribeOwner(ctx, &ownerSubscription); err != nil {
+			slog.Error(
+				"[IMPORTER] Owner sync error",
+				"user", ownerSubscription.UserID,
+				"owner", ownerSubscription.Owner,
+				"error", err,
+			)
+		}
+	}
+}
+
+func matchOwnerSubscription(ownerSubscription *entities.OwnerSubscription, repository *github.RepositoryInfo) bool {
+	if ownerSubscription.SkipArchived && repository.Archived {
+		return false
+	}
+
+	if ownerSubscription.SkipForks && repository.Fork {
+		return false
+	}
+
+	_, name, _ := strings.Cut(repository.FullName, "/")
+
+	if ownerSubscription.IncludePattern != "" && !matchPattern(ownerSubscription.IncludePattern, name) {
+		return false
+	}
+
+	return ownerSubscription.ExcludePattern == "" || !matchPattern(ownerSubscription.ExcludePattern, name)
+}
+
+func matchPattern(pattern string, name string) bool {
+	matched, err := path.Match(pattern, name)
+
+	return err == nil && matched
+}
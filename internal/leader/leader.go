@@ -0,0 +1,115 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/config"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/repo"
+)
+
+const (
+	leaseName string = "release-monitor"
+	// renewalsPerTTL defines how often the lease is renewed, it leaves time for a couple of failed attempts
+	renewalsPerTTL = 3
+)
+
+// Elector runs leader-only jobs (polling GitHub and notifications) on a single bot instance, with a webhook
+// every instance serves commands, with the long poll only the leader does. Leadership moves to another instance
+// when the lease expires.
+type Elector struct {
+	repository *repo.Repository
+	holder     string
+	ttl        time.Duration
+}
+
+func NewElector(cfg *config.Config, repository *repo.Repository) *Elector {
+	holder := cfg.InstanceID
+	if holder == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "unknown"
+		}
+
+		holder = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+
+	return &Elector{repository: repository, holder: holder, ttl: cfg.LeaderLeaseTTL}
+}
+
+// Run campaigns for leadership and runs the job while this instance holds the lease.
+// The job context is canceled as soon as the leadership is lost.
+func (e *Elector) Run(ctx context.Context, job func(ctx context.Context)) {
+	slog.Info("[LEADER] Starting leader election...", "holder", e.holder)
+
+	ticker := time.NewTicker(e.ttl / renewalsPerTTL)
+	defer ticker.Stop()
+
+	for {
+		if e.acquire(ctx) {
+			e.lead(ctx, ticker, job)
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.Info("[LEADER] Close leader election...")
+
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Elector) lead(ctx context.Context, ticker *time.Ticker, job func(ctx context.Context)) {
+	slog.Info("[LEADER] Leadership acquired", "holder", e.holder)
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		job(leaderCtx)
+	}()
+
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			cancel()
+			<-done
+			// the lease is released with a fresh context, since the main one is already canceled
+			if err := e.repository.ReleaseLease(context.WithoutCancel(ctx), leaseName, e.holder); err != nil {
+				slog.Error("[LEADER] Lease release failed", "holder", e.holder, "error", err)
+			}
+
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+			if !e.acquire(ctx) {
+				slog.Warn("[LEADER] Leadership lost", "holder", e.holder)
+
+				return
+			}
+		}
+	}
+}
+
+func (e *Elector) acquire(ctx context.Context) bool {
+	acquired, err := e.repository.AcquireLease(ctx, leaseName, e.holder, time.Now(), e.ttl)
+	if err != nil {
+		slog.Error("[LEADER] Lease acquisition failed", "holder", e.holder, "error", err)
+
+		return false
+	}
+
+	return acquired
+}
@@ -0,0 +1,195 @@
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+type Ecosystem string
+
+const (
+	EcosystemGo    Ecosystem = "go"
+	EcosystemNpm   Ecosystem = "npm"
+	EcosystemPyPI  Ecosystem = "pypi"
+	EcosystemCargo Ecosystem = "cargo"
+	EcosystemMaven Ecosystem = "maven"
+)
+
+var ErrUnsupportedManifest = errors.New("unsupported manifest file")
+
+var (
+	requirementNamePattern = regexp.MustCompile(`^([A-Za-z\d][A-Za-z\d._-]*)`)
+	cargoKeyPattern        = regexp.MustCompile(`^([A-Za-z\d_-]+)\s*=`)
+	cargoTablePattern      = regexp.MustCompile(`^\[((?:workspace\.)?(?:dev-|build-)?dependencies)(?:\.([A-Za-z\d_-]+))?]$`)
+)
+
+type Dependency struct {
+	Ecosystem Ecosystem
+	Name      string
+}
+
+// Parse extracts dependencies from the manifest content, the manifest type is detected by the file name.
+func Parse(fileName string, content []byte) ([]Dependency, error) {
+	switch path.Base(fileName) {
+	case "go.mod":
+		return parseGoMod(content)
+	case "package.json":
+		return parsePackageJSON(content)
+	case "requirements.txt":
+		return parseRequirements(content)
+	case "Cargo.toml":
+		return parseCargoToml(content)
+	case "pom.xml":
+		return parsePomXML(content)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedManifest, fileName)
+	}
+}
+
+func parseGoMod(content []byte) ([]Dependency, error) {
+	var (
+		dependencies []Dependency
+		inRequire    bool
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "require (":
+			inRequire = true
+
+			continue
+		case inRequire && line == ")":
+			inRequire = false
+
+			continue
+		case strings.HasPrefix(line, "require "):
+			line = strings.TrimPrefix(line, "require ")
+		case !inRequire:
+			continue
+		}
+
+		if strings.Contains(line, "// indirect") {
+			continue
+		}
+
+		if fields := strings.Fields(line); len(fields) >= 2 && !strings.HasPrefix(fields[0], "//") {
+			dependencies = append(dependencies, Dependency{Ecosystem: EcosystemGo, Name: fields[0]})
+		}
+	}
+
+	return dependencies, scanner.Err()
+}
+
+func parsePackageJSON(content []byte) ([]Dependency, error) {
+	var packageJSON struct {
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+
+	if err := json.Unmarshal(content, &packageJSON); err != nil {
+		return nil, fmt.Errorf("package.json decoding failed: %w", err)
+	}
+
+	dependencies := make([]Dependency, 0, len(packageJSON.Dependencies)+len(packageJSON.DevDependencies))
+
+	for _, group := range []map[string]string{packageJSON.Dependencies, packageJSON.DevDependencies} {
+		for name := range group {
+			dependencies = append(dependencies, Dependency{Ecosystem: EcosystemNpm, Name: name})
+		}
+	}
+
+	return dependencies, nil
+}
+
+func parseRequirements(content []byte) ([]Dependency, error) {
+	var dependencies []Dependency
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "-") {
+			continue
+		}
+
+		if matches := requirementNamePattern.FindStringSubmatch(line); len(matches) > 0 {
+			dependencies = append(dependencies, Dependency{Ecosystem: EcosystemPyPI, Name: matches[1]})
+		}
+	}
+
+	return dependencies, scanner.Err()
+}
+
+func parseCargoToml(content []byte) ([]Dependency, error) {
+	var (
+		dependencies []Dependency
+		inTable      bool
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			matches := cargoTablePattern.FindStringSubmatch(line)
+			// [dependencies.name] tables declare a single dependency
+			inTable = len(matches) > 0 && matches[2] == ""
+
+			if len(matches) > 0 && matches[2] != "" {
+				dependencies = append(dependencies, Dependency{Ecosystem: EcosystemCargo, Name: matches[2]})
+			}
+
+			continue
+		}
+
+		if !inTable {
+			continue
+		}
+
+		if matches := cargoKeyPattern.FindStringSubmatch(line); len(matches) > 0 {
+			dependencies = append(dependencies, Dependency{Ecosystem: EcosystemCargo, Name: matches[1]})
+		}
+	}
+
+	return dependencies, scanner.Err()
+}
+
+func parsePomXML(content []byte) ([]Dependency, error) {
+	var pom struct {
+		Dependencies []struct {
+			GroupID    string `xml:"groupId"`
+			ArtifactID string `xml:"artifactId"`
+		} `xml:"dependencies>dependency"`
+	}
+
+	if err := xml.Unmarshal(content, &pom); err != nil {
+		return nil, fmt.Errorf("pom.xml decoding failed: %w", err)
+	}
+
+	dependencies := make([]Dependency, 0, len(pom.Dependencies))
+
+	for _, dependency := range pom.Dependencies {
+		groupID := strings.TrimSpace(dependency.GroupID)
+		artifactID := strings.TrimSpace(dependency.ArtifactID)
+		// property placeholders can't be resolved without the whole project model
+		if groupID == "" || artifactID == "" || strings.Contains(groupID+artifactID, "${") {
+			continue
+		}
+
+		dependencies = append(dependencies, Dependency{Ecosystem: EcosystemMaven, Name: groupID + ":" + artifactID})
+	}
+
+	return dependencies, nil
+}
@@ -0,0 +1,280 @@
+package manifest
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	goProxyURLMask       string = "https://proxy.golang.org/%s/@latest"
+	npmRegistryURLMask   string = "https://registry.npmjs.org/%s/latest"
+	pypiURLMask          string = "https://pypi.org/pypi/%s/json"
+	cratesURLMask        string = "https://crates.io/api/v1/crates/%s"
+	mavenMetadataURLMask string = "https://repo1.maven.org/maven2/%s/%s/maven-metadata.xml"
+	mavenPomURLMask      string = "https://repo1.maven.org/maven2/%s/%s/%s/%s-%s.pom"
+	githubURLMask        string = "https://github.com/%s/%s"
+	// crates.io rejects requests without User-Agent
+	userAgent string = "github-release-monitor-bot"
+	// requestTimeout keeps a hanging registry from blocking the whole manifest import
+	requestTimeout time.Duration = 10 * time.Second
+)
+
+var ErrUnexpectedStatus = errors.New("unexpected response status")
+
+var githubSourcePattern = regexp.MustCompile(`(?:^|[/@.])github\.com[/:]([\w.-]+)/([\w.-]+?)(?:\.git)?(?:[/#?].*)?$`)
+
+type Resolver struct {
+	httpClient *http.Client
+}
+
+func NewResolver() *Resolver {
+	return &Resolver{httpClient: &http.Client{Timeout: requestTimeout}}
+}
+
+// Resolve returns the GitHub repository URL of the dependency source code
+// or an empty string if the dependency isn't hosted on GitHub.
+func (r *Resolver) Resolve(ctx context.Context, dependency Dependency) (string, error) {
+	switch dependency.Ecosystem {
+	case EcosystemGo:
+		return r.resolveGo(ctx, dependency.Name)
+	case EcosystemNpm:
+		return r.resolveNpm(ctx, dependency.Name)
+	case EcosystemPyPI:
+		return r.resolvePyPI(ctx, dependency.Name)
+	case EcosystemCargo:
+		return r.resolveCargo(ctx, dependency.Name)
+	case EcosystemMaven:
+		return r.resolveMaven(ctx, dependency.Name)
+	default:
+		return "", nil
+	}
+}
+
+func (r *Resolver) resolveGo(ctx context.Context, modulePath string) (string, error) {
+	if repositoryURL := NormalizeGithubURL(modulePath); repositoryURL != "" {
+		return repositoryURL, nil
+	}
+
+	var moduleInfo struct {
+		Origin struct {
+			URL string `json:"URL"`
+		} `json:"Origin"`
+	}
+
+	if err := r.getJSON(ctx, fmt.Sprintf(goProxyURLMask, escapeModulePath(modulePath)), &moduleInfo); err != nil {
+		return "", err
+	}
+
+	return NormalizeGithubURL(moduleInfo.Origin.URL), nil
+}
+
+func (r *Resolver) resolveNpm(ctx context.Context, name string) (string, error) {
+	var packageInfo struct {
+		Repository json.RawMessage `json:"repository"`
+	}
+
+	if err := r.getJSON(ctx, fmt.Sprintf(npmRegistryURLMask, url.PathEscape(name)), &packageInfo); err != nil {
+		return "", err
+	}
+
+	// repository field is either a plain string or an object with url
+	var repository struct {
+		URL string `json:"url"`
+	}
+
+	if err := json.Unmarshal(packageInfo.Repository, &repository); err == nil {
+		return NormalizeGithubURL(repository.URL), nil
+	}
+
+	var repositoryURL string
+	if err := json.Unmarshal(packageInfo.Repository, &repositoryURL); err == nil {
+		return NormalizeGithubURL(repositoryURL), nil
+	}
+
+	return "", nil
+}
+
+func (r *Resolver) resolvePyPI(ctx context.Context, name string) (string, error) {
+	var projectInfo struct {
+		Info struct {
+			HomePage    string            `json:"home_page"`
+			ProjectURLs map[string]string `json:"project_urls"`
+		} `json:"info"`
+	}
+
+	if err := r.getJSON(ctx, fmt.Sprintf(pypiURLMask, url.PathEscape(name)), &projectInfo); err != nil {
+		return "", err
+	}
+
+	for _, key := range []string{"Source", "Source Code", "Repository", "Code", "Homepage"} {
+		if repositoryURL := NormalizeGithubURL(projectInfo.Info.ProjectURLs[key]); repositoryURL != "" {
+			return repositoryURL, nil
+		}
+	}
+
+	for _, projectURL := range projectInfo.Info.ProjectURLs {
+		if repositoryURL := NormalizeGithubURL(projectURL); repositoryURL != "" {
+			return repositoryURL, nil
+		}
+	}
+
+	return NormalizeGithubURL(projectInfo.Info.HomePage), nil
+}
+
+func (r *Resolver) resolveCargo(ctx context.Context, name string) (string, error) {
+	var crateInfo struct {
+		Crate struct {
+			Repository string `json:"repository"`
+			Homepage   string `json:"homepage"`
+		} `json:"crate"`
+	}
+
+	if err := r.getJSON(ctx, fmt.Sprintf(cratesURLMask, url.PathEscape(name)), &crateInfo); err != nil {
+		return "", err
+	}
+
+	if repositoryURL := NormalizeGithubURL(crateInfo.Crate.Repository); repositoryURL != "" {
+		return repositoryURL, nil
+	}
+
+	return NormalizeGithubURL(crateInfo.Crate.Homepage), nil
+}
+
+func (r *Resolver) resolveMaven(ctx context.Context, name string) (string, error) {
+	groupID, artifactID, found := strings.Cut(name, ":")
+	if !found {
+		return "", nil
+	}
+
+	groupPath := strings.ReplaceAll(groupID, ".", "/")
+
+	var metadata struct {
+		Versioning struct {
+			Latest  string `xml:"latest"`
+			Release string `xml:"release"`
+		} `xml:"versioning"`
+	}
+
+	if err := r.getXML(ctx, fmt.Sprintf(mavenMetadataURLMask, groupPath, artifactID), &metadata); err != nil {
+		return "", err
+	}
+
+	version := metadata.Versioning.Release
+	if version == "" {
+		version = metadata.Versioning.Latest
+	}
+
+	if version == "" {
+		return "", nil
+	}
+
+	var pom struct {
+		URL string `xml:"url"`
+		SCM struct {
+			URL        string `xml:"url"`
+			Connection string `xml:"connection"`
+		} `xml:"scm"`
+	}
+
+	pomURL := fmt.Sprintf(mavenPomURLMask, groupPath, artifactID, version, artifactID, version)
+	if err := r.getXML(ctx, pomURL, &pom); err != nil {
+		return "", err
+	}
+
+	for _, sourceURL := range []string{pom.SCM.URL, pom.SCM.Connection, pom.URL} {
+		if repositoryURL := NormalizeGithubURL(sourceURL); repositoryURL != "" {
+			return repositoryURL, nil
+		}
+	}
+
+	return "", nil
+}
+
+func (r *Resolver) getJSON(ctx context.Context, requestURL string, target any) error {
+	body, err := r.get(ctx, requestURL)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if err := json.NewDecoder(body).Decode(target); err != nil {
+		return fmt.Errorf("[MANIFEST] response decoding failed: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Resolver) getXML(ctx context.Context, requestURL string, target any) error {
+	body, err := r.get(ctx, requestURL)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if err := xml.NewDecoder(body).Decode(target); err != nil {
+		return fmt.Errorf("[MANIFEST] response decoding failed: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Resolver) get(ctx context.Context, requestURL string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("[MANIFEST] get-request failed: %w", err)
+	}
+
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("[MANIFEST] get-request failed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+
+		return nil, fmt.Errorf("[MANIFEST] %w: %s %s", ErrUnexpectedStatus, requestURL, resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+// NormalizeGithubURL converts any kind of GitHub source reference (git+https, ssh, scm, tree links)
+// to the canonical repository URL or returns an empty string for non-GitHub sources.
+func NormalizeGithubURL(sourceURL string) string {
+	matches := githubSourcePattern.FindStringSubmatch(strings.TrimSpace(sourceURL))
+	if len(matches) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(githubURLMask, matches[1], matches[2])
+}
+
+// escapeModulePath implements the Go module proxy case-encoding: upper-case letters become "!" + lower-case.
+func escapeModulePath(modulePath string) string {
+	var escaped strings.Builder
+
+	for _, symbol := range modulePath {
+		if unicode.IsUpper(symbol) {
+			escaped.WriteRune('!')
+			escaped.WriteRune(unicode.ToLower(symbol))
+
+			continue
+		}
+
+		escaped.WriteRune(symbol)
+	}
+
+	return escaped.String()
+}
@@ -3,27 +3,51 @@ package github
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
-	"regexp"
-	"sort"
+	"time"
+
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/config"
+	"golang.org/x/time/rate"
 )
 
 const (
-	releaseURLMask string = "https://api.github.com/repos/%s/releases/latest"
-	tagsURLMask    string = "https://api.github.com/repos/%s/git/refs/tags"
-	releaseTagMask string = "https://github.com/%s/releases/tag/%s"
+	releaseURLMask  string = "https://api.github.com/repos/%s/releases/latest"
+	releasesURLMask string = "https://api.github.com/repos/%s/releases?per_page=%d"
+	tagsURLMask     string = "https://api.github.com/repos/%s/git/refs/tags"
+	releaseTagMask  string = "https://github.com/%s/releases/tag/%s"
+	starredURLMask  string = "https://api.github.com/users/%s/starred?per_page=%d&page=%d"
+	ownerURLMask    string = "https://api.github.com/users/%s/repos?type=owner&per_page=%d&page=%d"
+	pageSize        int    = 100
+	// maxPages bounds a repository list to 1000 entries, so a single user can't spend the whole API budget
+	maxPages int = 10
 )
 
-var tagURIPattern = regexp.MustCompile(`refs/tags/([\w\d\-\.]+)`) //nolint:gocritic // allows underscores in variable names
+var (
+	ErrUnexpectedStatus   = errors.New("unexpected response status")
+	ErrRepositoryNotFound = errors.New("repository not found")
+	ErrInvalidTagRef      = errors.New("invalid tag ref")
+)
 
 type Client struct {
 	httpClient *http.Client
+	// limiter is a token bucket shared by the background jobs, so their GitHub API requests fit one budget
+	limiter *rate.Limiter
+	// interactiveLimiter is the budget reserved for requests of waiting users
+	interactiveLimiter *rate.Limiter
+	token              string
 }
 
-func NewClient() *Client {
-	return &Client{httpClient: &http.Client{}}
+func NewClient(cfg *config.Config) *Client {
+	return &Client{
+		// timeout is applied per request, so waiting for the rate limiter doesn't consume it
+		httpClient:         &http.Client{Timeout: cfg.FetchingTimeout},
+		limiter:            rate.NewLimiter(rate.Every(cfg.FetchingStepPeriod), cfg.FetchingBurst),
+		interactiveLimiter: rate.NewLimiter(rate.Every(cfg.ManualStepPeriod), cfg.ManualBurst),
+		token:              cfg.GithubToken,
+	}
 }
 
 func (c *Client) GetLatestTagFromReleaseURI(
@@ -36,17 +60,30 @@ func (c *Client) GetLatestTagFromReleaseURI(
 	if err != nil {
 		slog.Error("[GITHUB-CLIENT] Latest tag for release uri request failed", "error", err)
 
-		return ReleaseInfo{}, nil
+		return ReleaseInfo{}, fmt.Errorf("[GITHUB-CLIENT] latest tag for release uri request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		// the repository has no releases, its tags are the fallback
+		return ReleaseInfo{}, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		slog.Error("[GITHUB-CLIENT] Latest tag for release uri unexpected status", "status", resp.Status)
+
+		return ReleaseInfo{}, fmt.Errorf(
+			"[GITHUB-CLIENT] latest tag for release uri: %w: %s", ErrUnexpectedStatus, resp.Status,
+		)
+	}
+
 	if err := json.NewDecoder(resp.Body).Decode(&releaseInfo); err != nil {
 		slog.Error("[GITHUB-CLIENT] Latest tag for release uri body decoder failed", "error", err)
 
 		return ReleaseInfo{}, fmt.Errorf("[GITHUB-CLIENT] latest tag for release uri body decoder failed: %w", err)
 	}
 
-	return ReleaseInfo{}, nil
+	return releaseInfo, nil
 }
 
 func (c *Client) GetLatestTagFromTagURI(
@@ -57,10 +94,21 @@ func (c *Client) GetLatestTagFromTagURI(
 	if err != nil {
 		slog.Error("[GITHUB-CLIENT] Latest tag for tag uri request failed", "error", err)
 
-		return ReleaseInfo{}, nil
+		return ReleaseInfo{}, fmt.Errorf("[GITHUB-CLIENT] latest tag for tag uri request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		// unknown repositories are expected here, the caller decides whether it's an error
+		return ReleaseInfo{}, fmt.Errorf("[GITHUB-CLIENT] latest tag for tag uri: %w", ErrRepositoryNotFound)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		slog.Error("[GITHUB-CLIENT] Latest tag for tag uri unexpected status", "status", resp.Status)
+
+		return ReleaseInfo{}, fmt.Errorf("[GITHUB-CLIENT] latest tag for tag uri: %w: %s", ErrUnexpectedStatus, resp.Status)
+	}
+
 	var tagInfoList []TagInfo
 
 	if err := json.NewDecoder(resp.Body).Decode(&tagInfoList); err != nil {
@@ -75,12 +123,12 @@ func (c *Client) GetLatestTagFromTagURI(
 		return ReleaseInfo{}, nil
 	}
 
-	sort.Slice(tagInfoList, func(i, j int) bool {
-		return tagInfoList[i].Ref > tagInfoList[j].Ref
-	})
+	tagName, err := latestTagName(tagInfoList)
+	if err != nil {
+		slog.Error("[GITHUB-CLIENT] Latest tag for tag uri parsing failed", "repository", repoShortName, "error", err)
 
-	tagInfo := tagInfoList[0]
-	tagName := tagURIPattern.FindStringSubmatch(tagInfo.Ref)[1]
+		return ReleaseInfo{}, fmt.Errorf("[GITHUB-CLIENT] latest tag for tag uri parsing failed: %w", err)
+	}
 
 	releaseInfo := ReleaseInfo{
 		TagName:   tagName,
@@ -90,11 +138,141 @@ func (c *Client) GetLatestTagFromTagURI(
 	return releaseInfo, nil
 }
 
+// GetRecentReleases returns up to count latest releases of the repository, the newest go first.
+func (c *Client) GetRecentReleases(
+	ctx context.Context,
+	repoShortName string,
+	count int,
+) ([]ReleaseInfo, error) {
+	resp, err := c.makeGetHTTPRequest(ctx, c.httpClient, fmt.Sprintf(releasesURLMask, repoShortName, count))
+	if err != nil {
+		slog.Error("[GITHUB-CLIENT] Recent releases request failed", "error", err)
+
+		return nil, fmt.Errorf("[GITHUB-CLIENT] recent releases request failed: %w", err)
+	}
+
+	var releases []ReleaseInfo
+
+	if err := decodeJSONResponse(resp, &releases); err != nil {
+		slog.Error("[GITHUB-CLIENT] Recent releases body decoder failed", "error", err)
+
+		return nil, fmt.Errorf("[GITHUB-CLIENT] recent releases body decoder failed: %w", err)
+	}
+
+	return releases, nil
+}
+
+func (c *Client) GetStarredRepositories(
+	ctx context.Context,
+	username string,
+) ([]RepositoryInfo, error) {
+	return c.listRepositories(ctx, starredURLMask, username)
+}
+
+// GetOwnerRepositories returns public repositories of the user or organization.
+func (c *Client) GetOwnerRepositories(
+	ctx context.Context,
+	owner string,
+) ([]RepositoryInfo, error) {
+	return c.listRepositories(ctx, ownerURLMask, owner)
+}
+
+func (c *Client) listRepositories(
+	ctx context.Context,
+	urlMask string,
+	owner string,
+) ([]RepositoryInfo, error) {
+	var repositories []RepositoryInfo
+
+	for page := 1; page <= maxPages; page++ {
+		resp, err := c.makeGetHTTPRequest(ctx, c.httpClient, fmt.Sprintf(urlMask, owner, pageSize, page))
+		if err != nil {
+			slog.Error("[GITHUB-CLIENT] Repository list request failed", "owner", owner, "error", err)
+
+			return nil, fmt.Errorf("[GITHUB-CLIENT] repository list request failed: %w", err)
+		}
+
+		var pageRepositories []RepositoryInfo
+
+		err = decodeJSONResponse(resp, &pageRepositories)
+		if err != nil {
+			slog.Error("[GITHUB-CLIENT] Repository list body decoder failed", "owner", owner, "error", err)
+
+			return nil, fmt.Errorf("[GITHUB-CLIENT] repository list body decoder failed: %w", err)
+		}
+
+		repositories = append(repositories, pageRepositories...)
+
+		if len(pageRepositories) < pageSize {
+			return repositories, nil
+		}
+	}
+
+	slog.Warn("[GITHUB-CLIENT] Repository list is truncated", "owner", owner, "count", len(repositories))
+
+	return repositories, nil
+}
+
+func decodeJSONResponse(resp *http.Response, target any) error {
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s", ErrUnexpectedStatus, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
 func (c *Client) makeGetHTTPRequest(ctx context.Context, httpClient *http.Client, url string) (*http.Response, error) {
+	limiter := c.limiter
+	if isInteractive(ctx) {
+		limiter = c.interactiveLimiter
+	}
+
+	if err := limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("[GITHUB-CLIENT] rate limiter wait failed: %w", err)
+	}
+
+	budget, ok := ctx.Value(requestBudgetKey{}).(*requestBudget)
+	if !ok {
+		return c.doGetHTTPRequest(ctx, httpClient, url)
+	}
+
+	remaining := budget.left()
+	if remaining <= 0 {
+		return nil, fmt.Errorf("[GITHUB-CLIENT] %w", ErrRequestTimeout)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, remaining)
+	startedAt := time.Now()
+	release := func() {
+		cancel()
+		budget.spend(time.Since(startedAt))
+	}
+
+	resp, err := c.doGetHTTPRequest(ctx, httpClient, url)
+	if err != nil {
+		release()
+
+		return nil, err
+	}
+
+	resp.Body = &budgetBody{ReadCloser: resp.Body, release: release}
+
+	return resp, nil
+}
+
+func (c *Client) doGetHTTPRequest(ctx context.Context, httpClient *http.Client, url string) (*http.Response, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
 	if err != nil {
 		return nil, fmt.Errorf("[GITHUB-CLIENT] get-request failed: %w", err)
 	}
 
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
 	return httpClient.Do(req)
 }
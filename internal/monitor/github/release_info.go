@@ -1,8 +1,18 @@
 package github
 
+import "time"
+
 type ReleaseInfo struct {
-	TagName   string `json:"tag_name"`
-	SourceURL string `json:"html_url"`
+	TagName     string      `json:"tag_name"`
+	SourceURL   string      `json:"html_url"`
+	PublishedAt time.Time   `json:"published_at"`
+	Body        string      `json:"body"`
+	Assets      []AssetInfo `json:"assets"`
+}
+
+type AssetInfo struct {
+	Name        string `json:"name"`
+	DownloadURL string `json:"browser_download_url"`
 }
 
 func (r *ReleaseInfo) IsZero() bool {
@@ -0,0 +1,8 @@
+package github
+
+type RepositoryInfo struct {
+	FullName string `json:"full_name"`
+	HTMLURL  string `json:"html_url"`
+	Archived bool   `json:"archived"`
+	Fork     bool   `json:"fork"`
+}
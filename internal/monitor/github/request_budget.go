@@ -0,0 +1,68 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+var ErrRequestTimeout = errors.New("request timeout is exceeded")
+
+type (
+	interactiveKey   struct{}
+	requestBudgetKey struct{}
+)
+
+// requestBudget is the time left for the requests sharing one timeout.
+type requestBudget struct {
+	mu        sync.Mutex
+	remaining time.Duration
+}
+
+// WithInteractive marks requests made on behalf of a waiting user, they use the interactive rate limiter,
+// so they aren't queued behind the survey.
+func WithInteractive(ctx context.Context) context.Context {
+	return context.WithValue(ctx, interactiveKey{}, true)
+}
+
+// WithRequestTimeout sets one timeout for all requests made with the context, like every request
+// of a repository check. Waiting for the rate limiter isn't counted.
+func WithRequestTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, requestBudgetKey{}, &requestBudget{remaining: timeout})
+}
+
+func isInteractive(ctx context.Context) bool {
+	interactive, ok := ctx.Value(interactiveKey{}).(bool)
+
+	return ok && interactive
+}
+
+func (b *requestBudget) left() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.remaining
+}
+
+func (b *requestBudget) spend(elapsed time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.remaining -= elapsed
+}
+
+// budgetBody charges the request time to the budget when the response is read.
+type budgetBody struct {
+	io.ReadCloser
+
+	once    sync.Once
+	release func()
+}
+
+func (b *budgetBody) Close() error {
+	b.once.Do(b.release)
+
+	return b.ReadCloser.Close()
+}
@@ -1,5 +1,27 @@
 package github
 
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const tagRefPrefix string = "refs/tags/"
+
 type TagInfo struct {
 	Ref string `json:"ref"`
 }
+
+// latestTagName returns the name of the greatest tag ref of the non-empty list, tag names may contain any characters.
+func latestTagName(tagInfoList []TagInfo) (string, error) {
+	latest := slices.MaxFunc(tagInfoList, func(a, b TagInfo) int {
+		return strings.Compare(a.Ref, b.Ref)
+	})
+
+	tagName, ok := strings.CutPrefix(latest.Ref, tagRefPrefix)
+	if !ok || tagName == "" {
+		return "", fmt.Errorf("%w: %q", ErrInvalidTagRef, latest.Ref)
+	}
+
+	return tagName, nil
+}
@@ -0,0 +1,35 @@
+package github
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLatestTagName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		refs    []string
+		tagName string
+		err     error
+	}{
+		{refs: []string{"refs/tags/v1.0.0", "refs/tags/v1.1.0"}, tagName: "v1.1.0"},
+		{refs: []string{"refs/tags/v1.0.0+build.5"}, tagName: "v1.0.0+build.5"},
+		{refs: []string{"refs/tags/release/2.0"}, tagName: "release/2.0"},
+		{refs: []string{"refs/tags/релиз-1"}, tagName: "релиз-1"},
+		{refs: []string{"refs/heads/main"}, err: ErrInvalidTagRef},
+		{refs: []string{"refs/tags/"}, err: ErrInvalidTagRef},
+	}
+
+	for _, test := range tests {
+		tagInfoList := make([]TagInfo, 0, len(test.refs))
+		for _, ref := range test.refs {
+			tagInfoList = append(tagInfoList, TagInfo{Ref: ref})
+		}
+
+		tagName, err := latestTagName(tagInfoList)
+		if tagName != test.tagName || !errors.Is(err, test.err) {
+			t.Errorf("latestTagName(%q) = %q, %v, want %q, %v", test.refs, tagName, err, test.tagName, test.err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/monitor/github"
+)
+
+const maxCachedReleases int = 1000
+
+// ReleaseLookup finds the latest release of any repository on demand, results and unknown repositories are
+// cached for a while, so repeated lookups of popular repositories don't spend the GitHub API budget.
+type ReleaseLookup struct {
+	githubClient *github.Client
+	ttl          time.Duration
+	mu           sync.Mutex
+	cache        map[string]cachedRelease
+}
+
+type cachedRelease struct {
+	releaseInfo github.ReleaseInfo
+	// notFound remembers unknown repositories, so lookups of typos don't reach GitHub again
+	notFound  bool
+	expiresAt time.Time
+}
+
+func NewReleaseLookup(githubClient *github.Client, ttl time.Duration) *ReleaseLookup {
+	return &ReleaseLookup{
+		githubClient: githubClient,
+		ttl:          ttl,
+		cache:        make(map[string]cachedRelease),
+	}
+}
+
+// GetLatestRelease returns the latest release of the repository, a zero release means it has no releases or tags.
+func (l *ReleaseLookup) GetLatestRelease(ctx context.Context, shortName string) (github.ReleaseInfo, error) {
+	key := strings.ToLower(shortName)
+	now := time.Now()
+
+	l.mu.Lock()
+	cached, ok := l.cache[key]
+	l.mu.Unlock()
+
+	if ok && now.Before(cached.expiresAt) {
+		if cached.notFound {
+			return github.ReleaseInfo{}, github.ErrRepositoryNotFound
+		}
+
+		return cached.releaseInfo, nil
+	}
+
+	releaseInfo, err := fetchLatestRelease(ctx, l.githubClient, shortName)
+	if errors.Is(err, github.ErrRepositoryNotFound) {
+		l.store(key, cachedRelease{notFound: true}, now)
+
+		return github.ReleaseInfo{}, err
+	}
+
+	if err != nil {
+		// other failures aren't cached, the next lookup tries again
+		return github.ReleaseInfo{}, err
+	}
+
+	l.store(key, cachedRelease{releaseInfo: releaseInfo}, now)
+
+	return releaseInfo, nil
+}
+
+func (l *ReleaseLookup) store(key string, cached cachedRelease, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.cache) >= maxCachedReleases {
+		for cachedKey, cached := range l.cache {
+			if !now.Before(cached.expiresAt) {
+				delete(l.cache, cachedKey)
+			}
+		}
+	}
+
+	if len(l.cache) >= maxCachedReleases {
+		slog.Warn("[GITHUB-MONITOR] Release lookup cache is full, it's cleared", "size", len(l.cache))
+		clear(l.cache)
+	}
+
+	cached.expiresAt = now.Add(l.ttl)
+	l.cache[key] = cached
+}
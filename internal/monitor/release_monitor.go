@@ -4,29 +4,40 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/soltanoff/go_github_release_monitor_bot/internal/config"
-	"github.com/soltanoff/go_github_release_monitor_bot/internal/controller"
 	"github.com/soltanoff/go_github_release_monitor_bot/internal/entities"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/importer"
 	"github.com/soltanoff/go_github_release_monitor_bot/internal/monitor/github"
 	"github.com/soltanoff/go_github_release_monitor_bot/internal/repo"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/templates"
 )
 
 type ReleaseMonitor struct {
 	cfg          *config.Config
-	bc           *controller.BotController
 	repository   *repo.Repository
 	githubClient *github.Client
+	importer     *importer.Importer
+	renderer     *templates.Renderer
+	lastSyncAt   time.Time
 }
 
 func NewReleaseMonitor(
 	cfg *config.Config,
-	bc *controller.BotController,
 	repository *repo.Repository,
+	githubClient *github.Client,
+	repositoryImporter *importer.Importer,
+	renderer *templates.Renderer,
 ) *ReleaseMonitor {
-	return &ReleaseMonitor{cfg: cfg, bc: bc, repository: repository, githubClient: github.NewClient()}
+	return &ReleaseMonitor{
+		cfg:          cfg,
+		repository:   repository,
+		githubClient: githubClient,
+		importer:     repositoryImporter,
+		renderer:     renderer,
+	}
 }
 
 func (rm *ReleaseMonitor) Start(ctx context.Context) {
@@ -35,8 +46,14 @@ func (rm *ReleaseMonitor) Start(ctx context.Context) {
 	slog.Info("[GITHUB-MONITOR] Close release monitor...")
 }
 
+// runReleaseMonitor wakes up every SurveyMinPeriod and checks only repositories which are due,
+// each repository has its own adaptive interval within SurveyMinPeriod and SurveyMaxPeriod.
+// The schedule is stored in the database, so after restart overdue repositories are checked
+// immediately and fresh ones wait until they are due.
 func (rm *ReleaseMonitor) runReleaseMonitor(ctx context.Context) {
-	ticker := time.NewTicker(rm.cfg.SurveyPeriod)
+	rm.dataCollector(ctx)
+
+	ticker := time.NewTicker(rm.cfg.SurveyMinPeriod)
 	defer ticker.Stop()
 
 	for {
@@ -47,64 +64,157 @@ func (rm *ReleaseMonitor) runReleaseMonitor(ctx context.Context) {
 			rm.dataCollector(ctx)
 			// we deliberately reset it, since we need to wait for the
 			// specified time from the moment the operation is completed
-			ticker.Reset(rm.cfg.SurveyPeriod)
+			ticker.Reset(rm.cfg.SurveyMinPeriod)
 		}
 	}
 }
 
 func (rm *ReleaseMonitor) dataCollector(ctx context.Context) {
-	ticker := time.NewTicker(rm.cfg.FetchingStepPeriod)
-	defer ticker.Stop()
-
 	slog.Info("[GITHUB-MONITOR] Start repos data collection")
 
-	repositories, err := rm.repository.GetAllRepositories(ctx)
+	if time.Since(rm.lastSyncAt) >= rm.cfg.SurveyPeriod {
+		rm.importer.Sync(ctx)
+		rm.cleanupOrphanedRepositories(ctx)
+		rm.lastSyncAt = time.Now()
+	}
+
+	repositories, err := rm.repository.GetDueRepositories(ctx, time.Now())
 	if err != nil {
 		slog.Error("[GITHUB-MONITOR] Repositories selection unexpected error", "error", err)
 
 		return
 	}
 
+	// the pace is set by the GitHub client rate limiter shared by all workers,
+	// so the pool size only bounds the number of in-flight checks
+	var workers sync.WaitGroup
+
+	semaphore := make(chan struct{}, rm.cfg.FetchingWorkers)
+
 	for index := range repositories {
+		if ctx.Err() != nil {
+			break
+		}
+
 		repository := repositories[index]
 
-		select {
-		case <-ctx.Done():
-			slog.Info("[GITHUB-MONITOR] Close data collector...")
+		semaphore <- struct{}{}
 
-			return
-		case <-ticker.C:
-			err := rm.checkLastRepositoryTag(ctx, &repository)
-			if err != nil {
-				slog.Error(
-					"[GITHUB-MONITOR] Data collection error",
-					"repository", repository.ShortName,
-					"error", err,
-				)
+		workers.Go(func() {
+			defer func() { <-semaphore }()
+
+			// errors are saved as the repository health and must not stop other workers
+			if err := rm.checkRepository(ctx, &repository); err != nil && ctx.Err() == nil {
+				slog.Error("[GITHUB-MONITOR] Data collection error", "repository", repository.ShortName, "error", err)
 			}
-			// we deliberately reset it, since we need to wait for the
-			// specified time from the moment the operation is completed
-			ticker.Reset(rm.cfg.FetchingStepPeriod)
-		}
+		})
+	}
+
+	workers.Wait()
+
+	if ctx.Err() != nil {
+		slog.Info("[GITHUB-MONITOR] Close data collector...")
+
+		return
 	}
 
 	slog.Info("[GITHUB-MONITOR] Repos data collection is completed")
 }
 
-func (rm *ReleaseMonitor) checkLastRepositoryTag(
+func (rm *ReleaseMonitor) cleanupOrphanedRepositories(ctx context.Context) {
+	removed, err := rm.repository.CleanupOrphanedRepositories(ctx, time.Now(), rm.cfg.OrphanRetention)
+	if err != nil {
+		slog.Error("[GITHUB-MONITOR] Orphaned repositories cleanup failed", "error", err)
+
+		return
+	}
+
+	if removed > 0 {
+		slog.Info("[GITHUB-MONITOR] Orphaned repositories removed", "count", removed)
+	}
+}
+
+// CheckRepository polls the repository right away outside the survey schedule,
+// a new release is handled the same way as by the scheduled check.
+func (rm *ReleaseMonitor) CheckRepository(ctx context.Context, repository *entities.Repository) error {
+	return rm.checkRepository(ctx, repository)
+}
+
+// GetRecentReleases fetches the latest releases of the repository from GitHub without storing them.
+func (rm *ReleaseMonitor) GetRecentReleases(
 	ctx context.Context,
 	repository *entities.Repository,
-) error {
-	releaseInfo, err := rm.githubClient.GetLatestTagFromReleaseURI(ctx, repository.ShortName)
+	count int,
+) ([]entities.Release, error) {
+	releasesInfo, err := rm.githubClient.GetRecentReleases(ctx, repository.ShortName, count)
 	if err != nil {
-		return fmt.Errorf("[GITHUB-MONITOR] cannot get latest tag for repository: %w", err)
+		return nil, fmt.Errorf("[GITHUB-MONITOR] cannot get recent releases: %w", err)
 	}
 
-	if releaseInfo.IsZero() {
-		releaseInfo, err = rm.githubClient.GetLatestTagFromTagURI(ctx, repository.ShortName)
-		if err != nil {
-			return fmt.Errorf("[GITHUB-MONITOR] cannot get latest tag for repository: %w", err)
+	releases := make([]entities.Release, 0, len(releasesInfo))
+
+	for _, releaseInfo := range releasesInfo {
+		release := entities.Release{
+			RepositoryID: repository.ID,
+			TagName:      releaseInfo.TagName,
+			URL:          releaseInfo.SourceURL,
+		}
+		if !releaseInfo.PublishedAt.IsZero() {
+			release.PublishedAt = &releaseInfo.PublishedAt
 		}
+
+		releases = append(releases, release)
+	}
+
+	return releases, nil
+}
+
+// checkRepository checks the latest tag and saves the repository health and the next check time.
+func (rm *ReleaseMonitor) checkRepository(ctx context.Context, repository *entities.Repository) error {
+	// every request of the check shares one timeout
+	checkErr := rm.checkLastRepositoryTag(github.WithRequestTimeout(ctx, rm.cfg.FetchingTimeout), repository)
+	if checkErr != nil && ctx.Err() != nil {
+		// the check is interrupted by the caller, so it says nothing about the repository health
+		return checkErr
+	}
+
+	now := time.Now()
+
+	if checkErr != nil {
+		repository.ConsecutiveFailures++
+		repository.LastError = entities.TruncateError(checkErr)
+	} else {
+		repository.ConsecutiveFailures = 0
+		repository.LastError = ""
+		repository.LastSuccessAt = &now
+	}
+
+	subscribers, err := rm.repository.CountSubscribers(ctx, repository.ID)
+	if err != nil {
+		slog.Error("[GITHUB-MONITOR] Count subscribers failed", "repository", repository.ShortName, "error", err)
+	}
+
+	repository.LastCheckedAt = &now
+	repository.NextCheckAt = now.Add(nextCheckInterval(rm.cfg, repository, subscribers, now))
+
+	if err := rm.repository.UpdateRepositoryCheck(ctx, repository); err != nil {
+		slog.Error("[GITHUB-MONITOR] Repository schedule update failed", "repository", repository.ShortName, "error", err)
+
+		return checkErr
+	}
+
+	slog.Info("[GITHUB-MONITOR] Next check scheduled", "repository", repository.ShortName, "at", repository.NextCheckAt)
+
+	return checkErr
+}
+
+func (rm *ReleaseMonitor) checkLastRepositoryTag(
+	ctx context.Context,
+	repository *entities.Repository,
+) error {
+	releaseInfo, err := fetchLatestRelease(ctx, rm.githubClient, repository.ShortName)
+	if err != nil {
+		return err
 	}
 
 	if repository.LatestTag == releaseInfo.TagName {
@@ -113,43 +223,127 @@ func (rm *ReleaseMonitor) checkLastRepositoryTag(
 		return nil
 	}
 
+	oldTag := repository.LatestTag
+
+	// the first fetch is a baseline, the version may be years old, so nobody is notified about it
+	var previousCheckAt *time.Time
+
+	if oldTag != "" {
+		// repositories stored before the check time was tracked fall back to their last update
+		updatedAt := repository.UpdatedAt
+		previousCheckAt = &updatedAt
+
+		if repository.LastCheckedAt != nil {
+			previousCheckAt = repository.LastCheckedAt
+		}
+	}
+
+	now := time.Now()
+
+	trackRelease(repository, releaseInfo.PublishedAt, now)
 	repository.LatestTag = releaseInfo.TagName
+	repository.LastDetectedAt = &now
+
+	release := newTemplateRelease(repository, oldTag, &releaseInfo)
+
+	texts, err := rm.renderNotifications(ctx, repository, previousCheckAt, release)
+	if err != nil {
+		return err
+	}
+
+	// notifications are delivered by the dispatcher from the outbox, which
+	// is written in the same transaction as the new tag
+	enqueued, err := rm.repository.UpdateRepositoryWithNotifications(ctx, repository, &repo.ReleaseUpdate{
+		OldTag:      oldTag,
+		ReleaseURL:  releaseInfo.SourceURL,
+		PublishedAt: releaseInfo.PublishedAt,
+		Texts:       texts,
+		Render: func(userTemplate string) string {
+			return rm.renderer.Render(userTemplate, release)
+		},
+		PreviousCheckAt: previousCheckAt,
+	})
+	if err != nil {
+		return fmt.Errorf("[GITHUB-MONITOR] failed to update repository: %w", err)
+	}
+
+	if previousCheckAt == nil {
+		slog.Info("[GITHUB-MONITOR] Baseline version saved", "repository", repository.ShortName, "tag", repository.LatestTag)
+
+		return nil
+	}
+
+	slog.Info(
+		"[GITHUB-MONITOR] Notifications enqueued",
+		"repository", repository.ShortName,
+		"tag", repository.LatestTag,
+		"count", enqueued,
+	)
+
+	return nil
+}
+
+// fetchLatestRelease returns the latest release of the repository, repositories without releases
+// fall back to their latest tag.
+func newTemplateRelease(
+	repository *entities.Repository,
+	oldTag string,
+	releaseInfo *github.ReleaseInfo,
+) *templates.Release {
+	release := templates.Release{
+		Repo:        repository.ShortName,
+		RepoURL:     repository.URL,
+		OldTag:      oldTag,
+		NewTag:      releaseInfo.TagName,
+		URL:         releaseInfo.SourceURL,
+		Notes:       releaseInfo.Body,
+		PublishedAt: releaseInfo.PublishedAt,
+	}
 
-	if err := rm.repository.UpdateRepository(ctx, repository); err != nil {
-		return fmt.Errorf("[GITHUB-MONITOR] failed to repository: %w", err)
+	for _, asset := range releaseInfo.Assets {
+		release.Assets = append(release.Assets, templates.Asset{Name: asset.Name, URL: asset.DownloadURL})
 	}
 
-	users, err := rm.repository.GetAllSubscribers(ctx, repository.ID)
+	return &release
+}
+
+// renderNotifications renders the release once per subscriber template before the transaction,
+// so templates don't hold the database lock. The baseline version has no notifications.
+func (rm *ReleaseMonitor) renderNotifications(
+	ctx context.Context,
+	repository *entities.Repository,
+	previousCheckAt *time.Time,
+	release *templates.Release,
+) (map[string]string, error) {
+	texts := make(map[string]string)
+	if previousCheckAt == nil {
+		return texts, nil
+	}
+
+	userTemplates, err := rm.repository.GetSubscriberTemplates(ctx, repository.ID)
 	if err != nil {
-		slog.Info("[GITHUB-MONITOR]Get subscribers failed", "repository", repository.ShortName, "error", err)
+		return nil, fmt.Errorf("[GITHUB-MONITOR] failed to get subscriber templates: %w", err)
+	}
 
-		return fmt.Errorf("[GITHUB-MONITOR] get subscribers failed: %w", err)
+	for _, userTemplate := range userTemplates {
+		texts[userTemplate] = rm.renderer.Render(userTemplate, release)
 	}
 
-	var answer strings.Builder
+	return texts, nil
+}
 
-	answer.WriteString("<b>Release tag</b>: ")
-	answer.WriteString(releaseInfo.SourceURL)
+func fetchLatestRelease(ctx context.Context, githubClient *github.Client, shortName string) (github.ReleaseInfo, error) {
+	releaseInfo, err := githubClient.GetLatestTagFromReleaseURI(ctx, shortName)
+	if err != nil {
+		return github.ReleaseInfo{}, fmt.Errorf("[GITHUB-MONITOR] cannot get latest tag for repository: %w", err)
+	}
 
-	// Source: https://core.telegram.org/bots/faq#my-bot-is-hitting-limits-how-do-i-avoid-this
-	// the API will not allow more than 30 messages per second or so
-	for _, user := range users {
-		err := rm.bc.SendMessage(ctx, user.ExternalID, answer.String(), false)
+	if releaseInfo.IsZero() {
+		releaseInfo, err = githubClient.GetLatestTagFromTagURI(ctx, shortName)
 		if err != nil {
-			slog.Warn(
-				"[GITHUB-MONITOR] Send message failed",
-				"repository", repository.ShortName,
-				"receiverID", user.ExternalID,
-				"error", err,
-			)
-		} else {
-			slog.Info(
-				"[GITHUB-MONITOR] Sending data to user",
-				"repository", repository.ShortName,
-				"receiverID", user.ExternalID,
-			)
+			return github.ReleaseInfo{}, fmt.Errorf("[GITHUB-MONITOR] cannot get latest tag for repository: %w", err)
 		}
 	}
 
-	return nil
+	return releaseInfo, nil
 }
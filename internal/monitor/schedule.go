@@ -0,0 +1,54 @@
+package monitor
+
+import (
+	"math"
+	"time"
+
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/config"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/entities"
+)
+
+// checksPerReleaseInterval is how many times a repository is checked during its usual gap between releases.
+const checksPerReleaseInterval = 8
+
+// nextCheckInterval adapts the polling interval to the repository release cadence: frequently releasing
+// and heavily subscribed repositories are checked more often, dormant ones less often.
+func nextCheckInterval(cfg *config.Config, repository *entities.Repository, subscribers int64, now time.Time) time.Duration {
+	interval := cfg.SurveyPeriod
+
+	if repository.LastReleaseAt != nil {
+		// a repository which is silent longer than usual is considered dormant
+		cadence := max(repository.ReleaseInterval, now.Sub(*repository.LastReleaseAt))
+		interval = cadence / checksPerReleaseInterval
+	}
+
+	if subscribers > 1 {
+		interval = time.Duration(float64(interval) / (1 + math.Log2(float64(subscribers))))
+	}
+
+	return min(max(interval, cfg.SurveyMinPeriod), cfg.SurveyMaxPeriod)
+}
+
+// trackRelease updates the repository release cadence with the newly detected release.
+func trackRelease(repository *entities.Repository, publishedAt time.Time, now time.Time) {
+	releasedAt := publishedAt
+	if releasedAt.IsZero() {
+		// the very first fetch of a tag without release date says nothing about the release time
+		if repository.LatestTag == "" {
+			return
+		}
+
+		releasedAt = now
+	}
+
+	if repository.LastReleaseAt != nil && releasedAt.After(*repository.LastReleaseAt) {
+		gap := releasedAt.Sub(*repository.LastReleaseAt)
+		if repository.ReleaseInterval == 0 {
+			repository.ReleaseInterval = gap
+		} else {
+			repository.ReleaseInterval = (repository.ReleaseInterval + gap) / 2 //nolint:mnd // moving average
+		}
+	}
+
+	repository.LastReleaseAt = &releasedAt
+}
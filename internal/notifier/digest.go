@@ -0,0 +1,221 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/entities"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/i18n"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/repo"
+)
+
+const (
+	digestCheckPeriod time.Duration = time.Minute
+	digestHeader      string        = "<b>Release digest</b>"
+	daysPerWeek       int           = 7
+)
+
+// Digester groups releases queued for users with daily or weekly delivery mode into a single message.
+type Digester struct {
+	repository *repo.Repository
+}
+
+func NewDigester(repository *repo.Repository) *Digester {
+	return &Digester{repository: repository}
+}
+
+func (d *Digester) Start(ctx context.Context) {
+	slog.Info("[DIGESTER] Starting digest sender...")
+	d.runDigester(ctx)
+	slog.Info("[DIGESTER] Close digest sender...")
+}
+
+func (d *Digester) runDigester(ctx context.Context) {
+	ticker := time.NewTicker(digestCheckPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.enqueueDigests(ctx)
+		}
+	}
+}
+
+func (d *Digester) enqueueDigests(ctx context.Context) {
+	users, err := d.repository.GetDigestUsers(ctx)
+	if err != nil {
+		slog.Error("[DIGESTER] Digest users selection unexpected error", "error", err)
+
+		return
+	}
+
+	now := time.Now()
+
+	for index := range users {
+		user := users[index]
+
+		periodStart := digestPeriodStart(&user, now)
+		if user.LastDigestAt != nil && !user.LastDigestAt.Before(periodStart) {
+			continue
+		}
+
+		if err := d.enqueueDigest(ctx, &user, periodStart, now); err != nil {
+			slog.Error("[DIGESTER] Digest enqueueing failed", "user", user.ID, "error", err)
+		}
+	}
+}
+
+func (d *Digester) enqueueDigest(ctx context.Context, user *entities.User, periodStart time.Time, now time.Time) error {
+	digestItems, err := d.repository.GetDigestItems(ctx, user)
+	if err != nil {
+		return fmt.Errorf("[DIGESTER] get digest items failed: %w", err)
+	}
+
+	var (
+		topics  []int
+		grouped = make(map[int][]entities.DigestItem)
+	)
+
+	// every forum topic gets its own digest with the releases of subscriptions made in it
+	for _, item := range digestItems {
+		if _, ok := grouped[item.MessageThreadID]; !ok {
+			topics = append(topics, item.MessageThreadID)
+		}
+
+		grouped[item.MessageThreadID] = append(grouped[item.MessageThreadID], item)
+	}
+
+	notifications := make([]entities.Notification, 0, len(topics))
+
+	for _, topic := range topics {
+		dedupKey := fmt.Sprintf("digest:%d:%d", user.ID, periodStart.Unix())
+		if topic != 0 {
+			dedupKey += fmt.Sprintf(":%d", topic)
+		}
+
+		// long digests are split into several messages, so every release is delivered
+		for part, text := range buildDigest(user.Language, grouped[topic]) {
+			partDedupKey := dedupKey
+			if part > 0 {
+				partDedupKey += fmt.Sprintf(":part%d", part)
+			}
+
+			notifications = append(notifications, entities.Notification{
+				DedupKey:        partDedupKey,
+				UserID:          user.ID,
+				ChatID:          user.ExternalID,
+				MessageThreadID: topic,
+				Text:            text,
+				Status:          entities.NotificationStatusPending,
+				NextAttemptAt:   now,
+			})
+		}
+	}
+
+	if err := d.repository.EnqueueDigest(ctx, user, digestItems, notifications, now); err != nil {
+		return fmt.Errorf("[DIGESTER] enqueue digest failed: %w", err)
+	}
+
+	if len(notifications) > 0 {
+		slog.Info("[DIGESTER] Digest enqueued", "user", user.ID, "releases", len(digestItems), "topics", len(topics))
+	}
+
+	return nil
+}
+
+// digestPeriodStart returns the start of the current digest period, the digest is due
+// if it wasn't sent since then. Leftovers of instant delivery mode are due immediately.
+func digestPeriodStart(user *entities.User, now time.Time) time.Time {
+	if user.DeliveryMode == entities.DeliveryModeInstant {
+		return now
+	}
+
+	now = now.In(user.Location())
+	periodStart := time.Date(now.Year(), now.Month(), now.Day(), user.DigestHour, 0, 0, 0, now.Location())
+	if periodStart.After(now) {
+		periodStart = periodStart.AddDate(0, 0, -1)
+	}
+
+	if user.DeliveryMode == entities.DeliveryModeWeekly {
+		daysSince := (int(periodStart.Weekday()) - user.DigestWeekday + daysPerWeek) % daysPerWeek
+		periodStart = periodStart.AddDate(0, 0, -daysSince)
+	}
+
+	return periodStart
+}
+
+// buildDigest lists each repository once with its version change over the whole period,
+// the list is split into messages which fit the Telegram limit.
+func buildDigest(language string, digestItems []entities.DigestItem) []string {
+	var (
+		order   []uint
+		grouped = make(map[uint]entities.DigestItem, len(digestItems))
+	)
+
+	for _, item := range digestItems {
+		first, ok := grouped[item.RepositoryID]
+		if !ok {
+			order = append(order, item.RepositoryID)
+			grouped[item.RepositoryID] = item
+
+			continue
+		}
+
+		item.OldTag = first.OldTag
+		grouped[item.RepositoryID] = item
+	}
+
+	var (
+		messages []string
+		answer   strings.Builder
+	)
+
+	header := i18n.T(language, digestHeader)
+
+	answer.WriteString(header)
+
+	for _, repositoryID := range order {
+		line := formatDigestItem(grouped[repositoryID])
+
+		if answer.Len()+len(line) > entities.MaxMessageLength {
+			messages = append(messages, answer.String())
+
+			answer.Reset()
+			answer.WriteString(header)
+		}
+
+		answer.WriteString(line)
+	}
+
+	return append(messages, answer.String())
+}
+
+func formatDigestItem(item entities.DigestItem) string {
+	var line strings.Builder
+
+	line.WriteString("\n• <a href=\"")
+	line.WriteString(html.EscapeString(item.RepositoryURL))
+	line.WriteString("\">")
+	line.WriteString(html.EscapeString(item.ShortName))
+	line.WriteString("</a>: ")
+
+	if item.OldTag != "" {
+		line.WriteString(html.EscapeString(item.OldTag))
+		line.WriteString(" → ")
+	}
+
+	line.WriteString("<a href=\"")
+	line.WriteString(html.EscapeString(item.ReleaseURL))
+	line.WriteString("\">")
+	line.WriteString(html.EscapeString(item.NewTag))
+	line.WriteString("</a>")
+
+	return line.String()
+}
@@ -0,0 +1,186 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/config"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/controller"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/entities"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/i18n"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/repo"
+)
+
+const (
+	dispatchBatchSize int           = 100
+	maxRetryDelay     time.Duration = time.Hour
+)
+
+// Dispatcher delivers notifications from the outbox. Every notification is enqueued exactly once
+// thanks to dedup keys, delivery failures are retried with exponential backoff. A crash between
+// sending and recording the delivery may still lead to a repeated message.
+type Dispatcher struct {
+	cfg        *config.Config
+	bc         *controller.BotController
+	repository *repo.Repository
+}
+
+func NewDispatcher(
+	cfg *config.Config,
+	bc *controller.BotController,
+	repository *repo.Repository,
+) *Dispatcher {
+	return &Dispatcher{cfg: cfg, bc: bc, repository: repository}
+}
+
+func (d *Dispatcher) Start(ctx context.Context) {
+	slog.Info("[DISPATCHER] Starting notification dispatcher...")
+	d.runDispatcher(ctx)
+	slog.Info("[DISPATCHER] Close notification dispatcher...")
+}
+
+func (d *Dispatcher) runDispatcher(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.DispatchPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.dispatch(ctx)
+			d.cleanup(ctx)
+			// we deliberately reset it, since we need to wait for the
+			// specified time from the moment the operation is completed
+			ticker.Reset(d.cfg.DispatchPeriod)
+		}
+	}
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context) {
+	notifications, err := d.repository.GetPendingNotifications(ctx, time.Now(), dispatchBatchSize)
+	if err != nil {
+		slog.Error("[DISPATCHER] Notifications selection unexpected error", "error", err)
+
+		return
+	}
+
+	// Source: https://core.telegram.org/bots/faq#my-bot-is-hitting-limits-how-do-i-avoid-this
+	// the API will not allow more than 30 messages per second or so
+	for index := range notifications {
+		if ctx.Err() != nil {
+			return
+		}
+
+		notification := notifications[index]
+		d.deliver(ctx, &notification)
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, notification *entities.Notification) {
+	now := time.Now()
+	options := controller.MessageOptions{MessageThreadID: notification.MessageThreadID}
+
+	if user := notification.User; user != nil {
+		if quietEnd, quiet := user.QuietUntil(now); quiet {
+			if user.QuietMode != entities.QuietModeSilent {
+				d.hold(ctx, notification, quietEnd)
+
+				return
+			}
+
+			options.DisableNotification = true
+		}
+	}
+
+	// release notifications have action buttons, digests cover many repositories and have none
+	if notification.RepositoryID != 0 {
+		language := i18n.DefaultLanguage
+		if notification.User != nil {
+			language = notification.User.Language
+		}
+
+		options.ReplyMarkup = d.bc.ReleaseKeyboard(
+			language,
+			notification.ChatID,
+			notification.RepositoryID,
+			notification.ReleaseURL,
+		)
+	}
+
+	err := d.bc.SendMessage(ctx, notification.ChatID, notification.Text, options)
+	now = time.Now()
+
+	notification.Attempts++
+
+	switch {
+	case err == nil:
+		notification.Status = entities.NotificationStatusSent
+		notification.SentAt = &now
+		notification.LastError = ""
+
+		slog.Info("[DISPATCHER] Notification sent", "notification", notification.ID, "receiverID", notification.ChatID)
+	case ctx.Err() != nil:
+		// shutdown isn't a delivery failure, the notification stays pending as is
+		return
+	default:
+		notification.LastError = entities.TruncateError(err)
+		// the bot was blocked or the chat is gone, retries won't help
+		if errors.Is(err, bot.ErrorForbidden) || notification.Attempts >= d.cfg.DispatchAttempts {
+			notification.Status = entities.NotificationStatusFailed
+		} else {
+			notification.NextAttemptAt = now.Add(d.retryDelay(notification.Attempts))
+		}
+
+		slog.Warn(
+			"[DISPATCHER] Notification delivery failed",
+			"notification", notification.ID,
+			"receiverID", notification.ChatID,
+			"attempts", notification.Attempts,
+			"status", notification.Status,
+			"error", err,
+		)
+	}
+
+	if err := d.repository.UpdateNotification(ctx, notification); err != nil {
+		slog.Error("[DISPATCHER] Notification state saving failed", "notification", notification.ID, "error", err)
+	}
+}
+
+// hold postpones the notification until the end of the recipient quiet hours, it isn't a delivery attempt.
+func (d *Dispatcher) hold(ctx context.Context, notification *entities.Notification, until time.Time) {
+	notification.NextAttemptAt = until
+
+	if err := d.repository.UpdateNotification(ctx, notification); err != nil {
+		slog.Error("[DISPATCHER] Notification state saving failed", "notification", notification.ID, "error", err)
+
+		return
+	}
+
+	slog.Info("[DISPATCHER] Notification held by quiet hours", "notification", notification.ID, "until", until)
+}
+
+func (d *Dispatcher) retryDelay(attempts int) time.Duration {
+	delay := d.cfg.DispatchRetryDelay << (attempts - 1)
+	if delay <= 0 || delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+
+	return delay
+}
+
+func (d *Dispatcher) cleanup(ctx context.Context) {
+	removed, err := d.repository.CleanupNotifications(ctx, time.Now().Add(-d.cfg.DispatchRetention))
+	if err != nil {
+		slog.Error("[DISPATCHER] Notifications cleanup failed", "error", err)
+
+		return
+	}
+
+	if removed > 0 {
+		slog.Info("[DISPATCHER] Old notifications removed", "count", removed)
+	}
+}
@@ -6,19 +6,30 @@ import (
 	"fmt"
 	"log/slog"
 	"strings"
+	"time"
 
 	"github.com/soltanoff/go_github_release_monitor_bot/internal/config"
 	"github.com/soltanoff/go_github_release_monitor_bot/internal/entities"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository struct {
 	db *gorm.DB
 }
 
+// busyTimeoutDSNOption makes concurrent writers wait for the SQLite lock instead of failing immediately.
+const busyTimeoutDSNOption string = "_busy_timeout=5000"
+
 func NewRepository(cfg *config.Config) (*Repository, error) {
-	db, err := gorm.Open(sqlite.Open(cfg.DBName), &gorm.Config{})
+	// DB_NAME may already have its own DSN options
+	separator := "?"
+	if strings.Contains(cfg.DBName, "?") {
+		separator = "&"
+	}
+
+	db, err := gorm.Open(sqlite.Open(cfg.DBName+separator+busyTimeoutDSNOption), &gorm.Config{})
 	if err != nil {
 		slog.Error("[DB] DB connection error", "error", err.Error())
 
@@ -29,7 +40,26 @@ func NewRepository(cfg *config.Config) (*Repository, error) {
 }
 
 func (r *Repository) AutoMigrate() error {
-	err := r.db.AutoMigrate(&entities.User{}, &entities.Repository{}, &entities.UserRepository{})
+	// chat ids are unique since chat migrations merge users, the older duplicates must go before the index is built
+	if r.db.Migrator().HasTable(&entities.User{}) && !r.db.Migrator().HasIndex(&entities.User{}, "ExternalID") {
+		if err := r.removeDuplicateUsers(); err != nil {
+			slog.Error("[DB] DB migration error", "error", err.Error())
+
+			return fmt.Errorf("[DB] failed to remove duplicate users: %w", err)
+		}
+	}
+
+	err := r.db.AutoMigrate(
+		&entities.User{},
+		&entities.Repository{},
+		&entities.Release{},
+		&entities.UserRepository{},
+		&entities.StarredImport{},
+		&entities.OwnerSubscription{},
+		&entities.Lease{},
+		&entities.Notification{},
+		&entities.DigestItem{},
+	)
 	// check error for panic
 	if err != nil {
 		slog.Error("[DB] DB migration error", "error", err.Error())
@@ -40,6 +70,62 @@ func (r *Repository) AutoMigrate() error {
 	return nil
 }
 
+// removeDuplicateUsers keeps the first user of every chat id.
+func (r *Repository) removeDuplicateUsers() error {
+	tx := r.db.Begin()
+
+	defer tx.Rollback()
+
+	firstUsers := tx.Session(&gorm.Session{NewDB: true}).
+		Unscoped().
+		Model(&entities.User{}).
+		Select("MIN(id)").
+		Group("external_id")
+	duplicates := tx.Session(&gorm.Session{NewDB: true}).
+		Unscoped().
+		Model(&entities.User{}).
+		Select("id").
+		Where("id NOT IN (?)", firstUsers)
+
+	if err := deleteUsers(tx, duplicates); err != nil {
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// deleteUsers removes the users along with their subscriptions and queued messages,
+// userIDs is a value or a subquery accepted by the IN condition.
+func deleteUsers(tx *gorm.DB, userIDs any) error {
+	models := []any{
+		&entities.UserRepository{},
+		&entities.StarredImport{},
+		&entities.OwnerSubscription{},
+		&entities.Notification{},
+		&entities.DigestItem{},
+	}
+	for _, model := range models {
+		// older databases may lack the tables created by later versions
+		if !tx.Migrator().HasTable(model) {
+			continue
+		}
+
+		if err := tx.Unscoped().Where("user_id IN (?)", userIDs).Delete(model).Error; err != nil {
+			slog.Error("[DB] User records removing failed", "model", fmt.Sprintf("%T", model), "error", err)
+
+			return err
+		}
+	}
+
+	if err := tx.Unscoped().Where("id IN (?)", userIDs).Delete(&entities.User{}).Error; err != nil {
+		slog.Error("[DB] User removing failed", "error", err)
+
+		return err
+	}
+
+	return nil
+}
+
 func (r *Repository) GetOrCreateUser(
 	ctx context.Context,
 	userExternalID int64,
@@ -53,11 +139,18 @@ func (r *Repository) GetOrCreateUser(
 	if err := tx.First(&user, "external_id = ?", userExternalID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			user = entities.User{ExternalID: userExternalID}
-			if err := tx.Create(&user).Error; err != nil {
+			// a concurrent update of the same chat may create the user first
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&user).Error; err != nil {
 				slog.Error("[DB] User creation failure", "senderID", userExternalID, "error", err)
 
 				return entities.User{}, err
 			}
+
+			if err := tx.First(&user, "external_id = ?", userExternalID).Error; err != nil {
+				slog.Error("[DB] User creation unexpected error", "senderID", userExternalID, "error", err)
+
+				return entities.User{}, err
+			}
 		} else {
 			slog.Error("[DB] User creation unexpected error", "senderID", userExternalID, "error", err)
 
@@ -72,6 +165,49 @@ func (r *Repository) GetOrCreateUser(
 	return user, nil
 }
 
+// MigrateUserChat moves the chat subscriber to the new chat id when a group is upgraded to a supergroup.
+func (r *Repository) MigrateUserChat(ctx context.Context, fromChatID int64, toChatID int64) error {
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	// the new chat may already have a user if it was used before the migration was handled,
+	// the migrated chat subscriptions and settings win
+	var target entities.User
+
+	if err := tx.Unscoped().Where("external_id = ?", toChatID).Limit(1).Find(&target).Error; err != nil {
+		slog.Error("[DB] Chat migration error", "fromChatID", fromChatID, "toChatID", toChatID, "error", err)
+
+		return err
+	}
+
+	if target.ID != 0 {
+		if err := deleteUsers(tx, target.ID); err != nil {
+			return err
+		}
+	}
+
+	query := tx.Model(&entities.User{}).Where("external_id = ?", fromChatID)
+	if err := query.Update("external_id", toChatID).Error; err != nil {
+		slog.Error("[DB] Chat migration error", "fromChatID", fromChatID, "toChatID", toChatID, "error", err)
+
+		return err
+	}
+
+	// pending notifications, including the ones held for quiet hours, are delivered to the new chat
+	query = tx.Model(&entities.Notification{}).
+		Where("chat_id = ? AND status = ?", fromChatID, entities.NotificationStatusPending)
+	if err := query.Update("chat_id", toChatID).Error; err != nil {
+		slog.Error("[DB] Chat migration error", "fromChatID", fromChatID, "toChatID", toChatID, "error", err)
+
+		return err
+	}
+
+	slog.Info("[DB] Chat migrated", "fromChatID", fromChatID, "toChatID", toChatID)
+
+	return tx.Commit().Error
+}
+
 func (r *Repository) GetAllUserSubscriptions(
 	ctx context.Context,
 	user *entities.User,
@@ -93,19 +229,118 @@ func (r *Repository) GetAllUserSubscriptions(
 	return selectedRepository, nil
 }
 
+// SubscriptionsSort is the order of the subscription list.
+type SubscriptionsSort string
+
+const (
+	SortByName    SubscriptionsSort = "name"
+	SortByRelease SubscriptionsSort = "release"
+)
+
+// GetUserSubscriptionsPage returns a page of the user subscriptions whose url contains the filter
+// and the number of all matching subscriptions.
+func (r *Repository) GetUserSubscriptionsPage(
+	ctx context.Context,
+	user *entities.User,
+	filter string,
+	sort SubscriptionsSort,
+	offset int,
+	limit int,
+) ([]entities.Repository, int64, error) {
+	var (
+		selectedRepository []entities.Repository
+		total              int64
+	)
+
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	query := tx.Model(&entities.Repository{}).
+		Joins("JOIN user_repositories ON repositories.id = user_repositories.repository_id").
+		Where("user_repositories.user_id = ? AND user_repositories.deleted_at IS NULL", user.ID)
+
+	if filter != "" {
+		query = query.Where(`LOWER(repositories.url) LIKE ? ESCAPE '\'`, "%"+escapeLike(strings.ToLower(filter))+"%")
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	order := "repositories.short_name"
+	if sort == SortByRelease {
+		// repositories without releases go last
+		order = "repositories.last_release_at IS NULL, repositories.last_release_at DESC, repositories.short_name"
+	}
+
+	err := query.Order(order).Offset(offset).Limit(limit).Find(&selectedRepository).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return selectedRepository, total, nil
+}
+
+func escapeLike(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(text)
+}
+
+// GetUserSubscription returns the repository with the given url only if the user is subscribed to it.
+func (r *Repository) GetUserSubscription(
+	ctx context.Context,
+	user *entities.User,
+	repositoryURL string,
+) (*entities.Repository, error) {
+	var repository entities.Repository
+
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	query := tx.Joins("JOIN user_repositories ON repositories.id = user_repositories.repository_id").
+		Where("user_repositories.user_id = ? AND repositories.url = ?", user.ID, repositoryURL)
+
+	err := query.First(&repository).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &repository, nil
+}
+
+// SubscriptionOrigin describes who makes the subscriptions and where their notifications are posted.
+type SubscriptionOrigin struct {
+	// MessageThreadID is the forum topic of the subscriptions, zero is the chat itself
+	MessageThreadID int
+	// OwnerSubscriptionID marks the subscriptions made by the owner subscription, they're dropped with it
+	OwnerSubscriptionID *uint
+	// Manual subscriptions are made by the user command, they take over the ones made by owner subscriptions
+	// and move the existing ones to their topic
+	Manual bool
+}
+
+// GetOrCreateUserRepository subscribes user to the repository, an existing subscription is moved
+// to the given forum topic. The flag reports whether the subscription is new.
 func (r *Repository) GetOrCreateUserRepository(
 	tx *gorm.DB,
 	user *entities.User,
 	repository *entities.Repository,
 	repositoryURL string,
-) (entities.UserRepository, error) {
+	origin SubscriptionOrigin,
+) (entities.UserRepository, bool, error) {
 	var userRepo entities.UserRepository
 
 	query := tx.Where("user_id = ? AND repository_id = ?", user.ID, repository.ID)
 
 	if err := query.First(&userRepo).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			userRepo = entities.UserRepository{UserID: user.ID, RepositoryID: repository.ID}
+			userRepo = entities.UserRepository{
+				UserID:              user.ID,
+				RepositoryID:        repository.ID,
+				MessageThreadID:     origin.MessageThreadID,
+				OwnerSubscriptionID: origin.OwnerSubscriptionID,
+			}
 			if err := tx.Create(&userRepo).Error; err != nil {
 				slog.Error(
 					"[DB] UserRepository creation failure",
@@ -114,10 +349,12 @@ func (r *Repository) GetOrCreateUserRepository(
 					"error", err,
 				)
 
-				return entities.UserRepository{}, err
+				return entities.UserRepository{}, false, err
 			}
 
 			slog.Info("[DB] Subscribe user", "user", user.ID, "url", repositoryURL)
+
+			return userRepo, true, nil
 		} else {
 			slog.Error(
 				"[DB] UserRepository check unexpected error",
@@ -126,11 +363,31 @@ func (r *Repository) GetOrCreateUserRepository(
 				"error", err,
 			)
 
-			return entities.UserRepository{}, err
+			return entities.UserRepository{}, false, err
+		}
+	}
+
+	updates := make(map[string]any)
+
+	// only an explicit subscription moves it to another topic, imports keep the topic it was made in
+	if origin.Manual && userRepo.MessageThreadID != origin.MessageThreadID {
+		updates["message_thread_id"] = origin.MessageThreadID
+	}
+
+	if origin.Manual && userRepo.OwnerSubscriptionID != nil {
+		// the user subscribed by hand, so unfollowing the owner keeps the subscription
+		updates["owner_subscription_id"] = nil
+	}
+
+	if len(updates) > 0 {
+		if err := tx.Model(&userRepo).Updates(updates).Error; err != nil {
+			slog.Error("[DB] UserRepository update failure", "user", user.ID, "repo", repository.ID, "error", err)
+
+			return entities.UserRepository{}, false, err
 		}
 	}
 
-	return entities.UserRepository{}, nil
+	return userRepo, false, nil
 }
 
 func (r *Repository) GetOrCreateRepositoryByURI(
@@ -162,11 +419,16 @@ func (r *Repository) GetOrCreateRepositoryByURI(
 	return repository, nil
 }
 
+// AddUserSubscription subscribes user to every repository url of the message
+// and returns the number of new subscriptions.
 func (r *Repository) AddUserSubscription(
 	ctx context.Context,
 	user *entities.User,
 	receivedMessage string,
-) error {
+	origin SubscriptionOrigin,
+) (int, error) {
+	var created int
+
 	tx := r.db.Begin().WithContext(ctx)
 
 	defer tx.Rollback()
@@ -181,16 +443,20 @@ func (r *Repository) AddUserSubscription(
 
 		repository, err := r.GetOrCreateRepositoryByURI(tx, repositoryURL, uriMatches[1])
 		if err != nil {
-			return err
+			return 0, err
 		}
 
-		_, err = r.GetOrCreateUserRepository(tx, user, &repository, repositoryURL)
+		_, isNew, err := r.GetOrCreateUserRepository(tx, user, &repository, repositoryURL, origin)
 		if err != nil {
-			return err
+			return 0, err
+		}
+
+		if isNew {
+			created++
 		}
 	}
 
-	return tx.Commit().Error
+	return created, tx.Commit().Error
 }
 
 func (r *Repository) RemoveUserSubscription(
@@ -243,6 +509,65 @@ func (r *Repository) RemoveUserSubscription(
 	return tx.Commit().Error
 }
 
+// RemoveUserSubscriptionByID unsubscribes the user from the repository, unknown subscriptions are ignored.
+func (r *Repository) RemoveUserSubscriptionByID(
+	ctx context.Context,
+	user *entities.User,
+	repositoryID uint,
+) error {
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	query := tx.Unscoped().Where("user_id = ? AND repository_id = ?", user.ID, repositoryID)
+	if err := query.Delete(&entities.UserRepository{}).Error; err != nil {
+		slog.Error("[DB] Unsubscribe user error", "user", user.ID, "repository", repositoryID, "error", err)
+
+		return err
+	}
+
+	slog.Info("[DB] Unsubscribe user", "user", user.ID, "repository", repositoryID)
+
+	return tx.Commit().Error
+}
+
+// MuteUserSubscription suspends notifications about the repository until the given time.
+func (r *Repository) MuteUserSubscription(
+	ctx context.Context,
+	user *entities.User,
+	repositoryID uint,
+	until time.Time,
+) (bool, error) {
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	query := tx.Model(&entities.UserRepository{}).Where("user_id = ? AND repository_id = ?", user.ID, repositoryID)
+
+	result := query.Update("muted_until", until)
+	if result.Error != nil {
+		slog.Error("[DB] Subscription muting failed", "user", user.ID, "repository", repositoryID, "error", result.Error)
+
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, tx.Commit().Error
+}
+
+func (r *Repository) GetRepositoryByID(ctx context.Context, repositoryID uint) (*entities.Repository, error) {
+	var repository entities.Repository
+
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	if err := tx.First(&repository, repositoryID).Error; err != nil {
+		return nil, err
+	}
+
+	return &repository, nil
+}
+
 func (r *Repository) RemoveAllUserSubscriptions(
 	ctx context.Context,
 	user *entities.User,
@@ -270,18 +595,36 @@ func (r *Repository) RemoveAllUserSubscriptions(
 		}
 	}
 
+	query = tx.Unscoped().Where("user_id = ?", user.ID)
+
+	if err := query.Delete(&entities.StarredImport{}).Error; err != nil {
+		slog.Error("[DB] StarredImport removing failed", "user", user.ID, "error", err)
+
+		return err
+	}
+
+	query = tx.Unscoped().Where("user_id = ?", user.ID)
+
+	if err := query.Delete(&entities.OwnerSubscription{}).Error; err != nil {
+		slog.Error("[DB] OwnerSubscription removing failed", "user", user.ID, "error", err)
+
+		return err
+	}
+
 	return tx.Commit().Error
 }
 
-func (r *Repository) GetAllRepositories(ctx context.Context) ([]entities.Repository, error) {
+// GetDueRepositories returns subscribed repositories whose next check time has come, the most overdue go first.
+func (r *Repository) GetDueRepositories(ctx context.Context, now time.Time) ([]entities.Repository, error) {
 	var repositories []entities.Repository
 
 	tx := r.db.Begin().WithContext(ctx)
 
 	defer tx.Rollback()
 
-	if err := tx.Find(&repositories).Error; err != nil {
-		slog.Error("[DB] Get all repositories failed", "error", err)
+	query := tx.Where("next_check_at <= ? AND EXISTS (?)", now, r.subscriptionsQuery(tx)).Order("next_check_at")
+	if err := query.Find(&repositories).Error; err != nil {
+		slog.Error("[DB] Get due repositories failed", "error", err)
 
 		return nil, err
 	}
@@ -289,6 +632,63 @@ func (r *Repository) GetAllRepositories(ctx context.Context) ([]entities.Reposit
 	return repositories, nil
 }
 
+// CleanupOrphanedRepositories marks repositories without subscribers as orphaned and deletes
+// the ones which stay orphaned longer than the retention period.
+func (r *Repository) CleanupOrphanedRepositories(
+	ctx context.Context,
+	now time.Time,
+	retention time.Duration,
+) (int64, error) {
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	query := tx.Model(&entities.Repository{}).Where("orphaned_at IS NOT NULL AND EXISTS (?)", r.subscriptionsQuery(tx))
+	if err := query.Update("orphaned_at", nil).Error; err != nil {
+		slog.Error("[DB] Repositories restoring failed", "error", err)
+
+		return 0, err
+	}
+
+	query = tx.Model(&entities.Repository{}).Where("orphaned_at IS NULL AND NOT EXISTS (?)", r.subscriptionsQuery(tx))
+	if err := query.Update("orphaned_at", now).Error; err != nil {
+		slog.Error("[DB] Repositories orphaning failed", "error", err)
+
+		return 0, err
+	}
+
+	expired := tx.Session(&gorm.Session{NewDB: true}).
+		Model(&entities.Repository{}).
+		Select("id").
+		Where("orphaned_at <= ? AND NOT EXISTS (?)", now.Add(-retention), r.subscriptionsQuery(tx))
+	// releases and queued messages about the repository are removed along with it
+	for _, model := range []any{&entities.Release{}, &entities.Notification{}, &entities.DigestItem{}} {
+		if err := tx.Unscoped().Where("repository_id IN (?)", expired).Delete(model).Error; err != nil {
+			slog.Error("[DB] Orphaned repository records removing failed", "model", fmt.Sprintf("%T", model), "error", err)
+
+			return 0, err
+		}
+	}
+
+	query = tx.Unscoped().Where("orphaned_at <= ? AND NOT EXISTS (?)", now.Add(-retention), r.subscriptionsQuery(tx))
+
+	result := query.Delete(&entities.Repository{})
+	if result.Error != nil {
+		slog.Error("[DB] Orphaned repositories removing failed", "error", result.Error)
+
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, tx.Commit().Error
+}
+
+func (r *Repository) subscriptionsQuery(tx *gorm.DB) *gorm.DB {
+	return tx.Session(&gorm.Session{NewDB: true}).
+		Table("user_repositories").
+		Select("1").
+		Where("user_repositories.repository_id = repositories.id AND user_repositories.deleted_at IS NULL")
+}
+
 func (r *Repository) UpdateRepository(
 	ctx context.Context,
 	repository *entities.Repository,
@@ -306,22 +706,679 @@ func (r *Repository) UpdateRepository(
 	return tx.Commit().Error
 }
 
-func (r *Repository) GetAllSubscribers(
-	ctx context.Context,
-	repositoryID uint,
-) ([]entities.User, error) {
-	var users []entities.User
+// ReleaseUpdate describes a newly detected release to notify subscribers about.
+type ReleaseUpdate struct {
+	OldTag      string
+	ReleaseURL  string
+	PublishedAt time.Time
+	// Texts are the notification texts by the subscriber template, they're rendered before the transaction
+	Texts map[string]string
+	// Render returns the notification text of a template missing in Texts, e.g. changed meanwhile
+	Render func(userTemplate string) string
+	// PreviousCheckAt is the time of the previous check, nil means the release is the first seen version
+	// which is saved as a baseline without notifications
+	PreviousCheckAt *time.Time
+}
 
+// UpdateRepositoryWithNotifications saves the repository new release and enqueues a notification
+// (or a digest item, depending on the delivery mode) for every subscriber in the same transaction,
+// so no subscriber is lost if the process crashes.
+func (r *Repository) UpdateRepositoryWithNotifications(
+	ctx context.Context,
+	repository *entities.Repository,
+	update *ReleaseUpdate,
+) (int64, error) {
 	tx := r.db.Begin().WithContext(ctx)
 
 	defer tx.Rollback()
 
-	query := tx.Joins("JOIN user_repositories ur on ur.user_id = users.id")
-	if err := query.Where("ur.repository_id = ?", repositoryID).Find(&users).Error; err != nil {
+	if err := tx.Save(repository).Error; err != nil {
+		slog.Error("[DB] Repository update failed", "error", err)
+
+		return 0, err
+	}
+
+	release := entities.Release{
+		RepositoryID: repository.ID,
+		TagName:      repository.LatestTag,
+		URL:          update.ReleaseURL,
+		DetectedAt:   time.Now(),
+	}
+	if !update.PublishedAt.IsZero() {
+		release.PublishedAt = &update.PublishedAt
+	}
+
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&release).Error; err != nil {
+		slog.Error("[DB] Release creation failed", "repository", repository.ID, "error", err)
+
+		return 0, err
+	}
+
+	if update.PreviousCheckAt == nil {
+		return 0, tx.Commit().Error
+	}
+
+	var subscriptions []entities.UserRepository
+
+	// subscribers who came after the previous check have the release as their baseline,
+	// unless it's known to be published after they subscribed
+	query := tx.Preload("User").
+		Where("repository_id = ?", repository.ID).
+		Where("muted_until IS NULL OR muted_until <= ?", time.Now()).
+		Where("created_at <= ? OR created_at < ?", *update.PreviousCheckAt, update.PublishedAt)
+	if err := query.Find(&subscriptions).Error; err != nil {
 		slog.Error("[DB] Get all subscribers failed", "error", err)
 
+		return 0, err
+	}
+
+	var (
+		notifications []entities.Notification
+		digestItems   []entities.DigestItem
+		// the publish time tells apart a tag which was deleted and released again, tags have none
+		publishedAt int64
+	)
+
+	if !update.PublishedAt.IsZero() {
+		publishedAt = update.PublishedAt.Unix()
+	}
+
+	for _, subscription := range subscriptions {
+		if subscription.User == nil {
+			continue
+		}
+
+		user := *subscription.User
+		dedupKey := fmt.Sprintf("release:%d:%s:%d:%d", repository.ID, repository.LatestTag, publishedAt, user.ID)
+
+		if user.DeliveryMode != entities.DeliveryModeInstant {
+			digestItems = append(digestItems, entities.DigestItem{
+				DedupKey:        dedupKey,
+				UserID:          user.ID,
+				RepositoryID:    repository.ID,
+				ShortName:       repository.ShortName,
+				RepositoryURL:   repository.URL,
+				OldTag:          update.OldTag,
+				NewTag:          repository.LatestTag,
+				ReleaseURL:      update.ReleaseURL,
+				MessageThreadID: subscription.MessageThreadID,
+			})
+
+			continue
+		}
+
+		text, ok := update.Texts[user.NotificationTemplate]
+		if !ok {
+			text = update.Render(user.NotificationTemplate)
+		}
+
+		notifications = append(notifications, entities.Notification{
+			DedupKey:        dedupKey,
+			UserID:          user.ID,
+			RepositoryID:    repository.ID,
+			ChatID:          user.ExternalID,
+			MessageThreadID: subscription.MessageThreadID,
+			Text:            text,
+			ReleaseURL:      update.ReleaseURL,
+			Status:          entities.NotificationStatusPending,
+			NextAttemptAt:   time.Now(),
+		})
+	}
+
+	var enqueued int64
+
+	if len(notifications) > 0 {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&notifications)
+		if result.Error != nil {
+			slog.Error("[DB] Notifications creation failed", "repository", repository.ID, "error", result.Error)
+
+			return 0, result.Error
+		}
+
+		enqueued += result.RowsAffected
+	}
+
+	if len(digestItems) > 0 {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&digestItems)
+		if result.Error != nil {
+			slog.Error("[DB] Digest items creation failed", "repository", repository.ID, "error", result.Error)
+
+			return 0, result.Error
+		}
+
+		enqueued += result.RowsAffected
+	}
+
+	return enqueued, tx.Commit().Error
+}
+
+// GetReleaseHistory returns the latest releases of the repository with the given url, the newest go first.
+// Releases are ordered by the publication time, tags without releases by the detection time.
+func (r *Repository) GetReleaseHistory(
+	ctx context.Context,
+	repositoryURL string,
+	limit int,
+) ([]entities.Release, error) {
+	var releases []entities.Release
+
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	query := tx.Joins("JOIN repositories ON repositories.id = releases.repository_id").
+		Where("repositories.url = ?", repositoryURL).
+		Order("COALESCE(releases.published_at, releases.detected_at) DESC").
+		Limit(limit)
+	if err := query.Find(&releases).Error; err != nil {
+		slog.Error("[DB] Get release history failed", "error", err)
+
 		return nil, err
 	}
 
-	return users, nil
+	return releases, nil
+}
+
+// GetFailingRepositories returns subscribed repositories with the longest series of failed checks.
+func (r *Repository) GetFailingRepositories(ctx context.Context, limit int) ([]entities.Repository, error) {
+	var repositories []entities.Repository
+
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	query := tx.Where("consecutive_failures > 0 AND EXISTS (?)", r.subscriptionsQuery(tx)).
+		Order("consecutive_failures DESC").
+		Limit(limit)
+	if err := query.Find(&repositories).Error; err != nil {
+		slog.Error("[DB] Get failing repositories failed", "error", err)
+
+		return nil, err
+	}
+
+	return repositories, nil
+}
+
+func (r *Repository) CountSubscribers(
+	ctx context.Context,
+	repositoryID uint,
+) (int64, error) {
+	var count int64
+
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	query := tx.Model(&entities.UserRepository{}).Where("repository_id = ?", repositoryID)
+	if err := query.Count(&count).Error; err != nil {
+		slog.Error("[DB] Count subscribers failed", "error", err)
+
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// GetSubscriberTemplates returns the distinct notification templates of the instant subscribers of the repository.
+func (r *Repository) GetSubscriberTemplates(
+	ctx context.Context,
+	repositoryID uint,
+) ([]string, error) {
+	var userTemplates []string
+
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	query := tx.Model(&entities.User{}).
+		Joins("JOIN user_repositories ON user_repositories.user_id = users.id").
+		Where("user_repositories.repository_id = ? AND user_repositories.deleted_at IS NULL", repositoryID).
+		Where("users.delivery_mode = ?", entities.DeliveryModeInstant).
+		Distinct().
+		Pluck("users.notification_template", &userTemplates)
+	if err := query.Error; err != nil {
+		slog.Error("[DB] Get subscriber templates failed", "repository", repositoryID, "error", err)
+
+		return nil, err
+	}
+
+	return userTemplates, nil
+}
+
+// UpdateRepositoryCheck saves only the polling schedule and health, so it never overwrites the release data.
+func (r *Repository) UpdateRepositoryCheck(
+	ctx context.Context,
+	repository *entities.Repository,
+) error {
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	query := tx.Model(repository).Select(
+		"next_check_at",
+		"last_checked_at",
+		"last_success_at",
+		"last_error",
+		"consecutive_failures",
+	)
+	if err := query.Updates(repository).Error; err != nil {
+		slog.Error("[DB] Repository schedule update failed", "error", err)
+
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func (r *Repository) GetAllSubscribers(
+	ctx context.Context,
+	repositoryID uint,
+) ([]entities.User, error) {
+	var users []entities.User
+
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	query := tx.Joins("JOIN user_repositories ur on ur.user_id = users.id")
+	if err := query.Where("ur.repository_id = ?", repositoryID).Find(&users).Error; err != nil {
+		slog.Error("[DB] Get all subscribers failed", "error", err)
+
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (r *Repository) AddStarredImport(
+	ctx context.Context,
+	user *entities.User,
+	githubUsername string,
+	messageThreadID int,
+) error {
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	var starredImport entities.StarredImport
+
+	query := tx.Where("user_id = ? AND github_username = ?", user.ID, githubUsername)
+
+	if err := query.First(&starredImport).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			slog.Error("[DB] StarredImport check unexpected error", "user", user.ID, "error", err)
+
+			return err
+		}
+
+		starredImport = entities.StarredImport{
+			UserID:          user.ID,
+			GithubUsername:  githubUsername,
+			MessageThreadID: messageThreadID,
+		}
+		if err := tx.Create(&starredImport).Error; err != nil {
+			slog.Error("[DB] StarredImport creation failure", "user", user.ID, "error", err)
+
+			return err
+		}
+
+		slog.Info("[DB] Enable stars sync", "user", user.ID, "githubUsername", githubUsername)
+	} else if starredImport.MessageThreadID != messageThreadID {
+		if err := tx.Model(&starredImport).Update("message_thread_id", messageThreadID).Error; err != nil {
+			slog.Error("[DB] StarredImport topic update failure", "user", user.ID, "error", err)
+
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+func (r *Repository) RemoveStarredImport(
+	ctx context.Context,
+	user *entities.User,
+	githubUsername string,
+) error {
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	query := tx.Unscoped().Where("user_id = ? AND github_username = ?", user.ID, githubUsername)
+
+	if err := query.Delete(&entities.StarredImport{}).Error; err != nil {
+		slog.Error("[DB] StarredImport removing failed", "user", user.ID, "error", err)
+
+		return err
+	}
+
+	slog.Info("[DB] Disable stars sync", "user", user.ID, "githubUsername", githubUsername)
+
+	return tx.Commit().Error
+}
+
+func (r *Repository) GetAllStarredImports(ctx context.Context) ([]entities.StarredImport, error) {
+	var starredImports []entities.StarredImport
+
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	if err := tx.Preload("User").Find(&starredImports).Error; err != nil {
+		slog.Error("[DB] Get all starred imports failed", "error", err)
+
+		return nil, err
+	}
+
+	return starredImports, nil
+}
+
+func (r *Repository) AddOwnerSubscription(
+	ctx context.Context,
+	ownerSubscription *entities.OwnerSubscription,
+) error {
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	var existing entities.OwnerSubscription
+
+	// GitHub names are case-insensitive
+	query := tx.Where("user_id = ? AND lower(owner) = lower(?)", ownerSubscription.UserID, ownerSubscription.Owner)
+
+	if err := query.First(&existing).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			slog.Error("[DB] OwnerSubscription check unexpected error", "user", ownerSubscription.UserID, "error", err)
+
+			return err
+		}
+	} else {
+		ownerSubscription.Model = existing.Model
+	}
+
+	if err := tx.Omit("User").Save(ownerSubscription).Error; err != nil {
+		slog.Error("[DB] OwnerSubscription saving failure", "user", ownerSubscription.UserID, "error", err)
+
+		return err
+	}
+
+	slog.Info("[DB] Subscribe user to owner", "user", ownerSubscription.UserID, "owner", ownerSubscription.Owner)
+
+	return tx.Commit().Error
+}
+
+// RemoveOwnerSubscription stops following the owner and unsubscribes user from the repositories
+// the owner subscription made, the ones subscribed by hand are kept.
+func (r *Repository) RemoveOwnerSubscription(
+	ctx context.Context,
+	user *entities.User,
+	owner string,
+) error {
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	var ownerSubscriptions []entities.OwnerSubscription
+
+	// GitHub names are case-insensitive
+	query := tx.Where("user_id = ? AND lower(owner) = lower(?)", user.ID, owner)
+
+	if err := query.Find(&ownerSubscriptions).Error; err != nil {
+		slog.Error("[DB] OwnerSubscription check unexpected error", "user", user.ID, "owner", owner, "error", err)
+
+		return err
+	}
+
+	for index := range ownerSubscriptions {
+		ownerSubscription := ownerSubscriptions[index]
+
+		query = tx.Unscoped().Where("user_id = ? AND owner_subscription_id = ?", user.ID, ownerSubscription.ID)
+
+		if err := query.Delete(&entities.UserRepository{}).Error; err != nil {
+			slog.Error("[DB] Unsubscribe user from owner error", "user", user.ID, "owner", owner, "error", err)
+
+			return err
+		}
+
+		if err := tx.Unscoped().Delete(&ownerSubscription).Error; err != nil {
+			slog.Error("[DB] OwnerSubscription removing failed", "user", user.ID, "owner", owner, "error", err)
+
+			return err
+		}
+	}
+
+	slog.Info("[DB] Unsubscribe user from owner", "user", user.ID, "owner", owner)
+
+	return tx.Commit().Error
+}
+
+func (r *Repository) GetAllOwnerSubscriptions(ctx context.Context) ([]entities.OwnerSubscription, error) {
+	var ownerSubscriptions []entities.OwnerSubscription
+
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	if err := tx.Preload("User").Find(&ownerSubscriptions).Error; err != nil {
+		slog.Error("[DB] Get all owner subscriptions failed", "error", err)
+
+		return nil, err
+	}
+
+	return ownerSubscriptions, nil
+}
+
+// AcquireLease takes or prolongs the named lease for the holder, it succeeds only if the lease
+// is free, expired or already held by the same holder.
+func (r *Repository) AcquireLease(
+	ctx context.Context,
+	name string,
+	holder string,
+	now time.Time,
+	ttl time.Duration,
+) (bool, error) {
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	query := tx.Model(&entities.Lease{}).Where("name = ? AND (holder = ? OR expires_at < ?)", name, holder, now)
+
+	result := query.Updates(map[string]any{"holder": holder, "expires_at": now.Add(ttl)})
+	if result.Error != nil {
+		slog.Error("[DB] Lease prolongation failed", "lease", name, "error", result.Error)
+
+		return false, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		lease := entities.Lease{Name: name, Holder: holder, ExpiresAt: now.Add(ttl)}
+
+		result = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&lease)
+		if result.Error != nil {
+			slog.Error("[DB] Lease creation failed", "lease", name, "error", result.Error)
+
+			return false, result.Error
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return false, err
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (r *Repository) ReleaseLease(
+	ctx context.Context,
+	name string,
+	holder string,
+) error {
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	query := tx.Model(&entities.Lease{}).Where("name = ? AND holder = ?", name, holder)
+	if err := query.Update("expires_at", time.Time{}).Error; err != nil {
+		slog.Error("[DB] Lease releasing failed", "lease", name, "error", err)
+
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func (r *Repository) GetPendingNotifications(
+	ctx context.Context,
+	now time.Time,
+	limit int,
+) ([]entities.Notification, error) {
+	var notifications []entities.Notification
+
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	query := tx.Preload("User").
+		Where("status = ? AND next_attempt_at <= ?", entities.NotificationStatusPending, now).
+		Order("next_attempt_at").
+		Limit(limit)
+	if err := query.Find(&notifications).Error; err != nil {
+		slog.Error("[DB] Get pending notifications failed", "error", err)
+
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+func (r *Repository) UpdateNotification(
+	ctx context.Context,
+	notification *entities.Notification,
+) error {
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	if err := tx.Omit("User").Save(notification).Error; err != nil {
+		slog.Error("[DB] Notification update failed", "notification", notification.ID, "error", err)
+
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// CleanupNotifications removes delivered and failed notifications created before the given time.
+func (r *Repository) CleanupNotifications(ctx context.Context, before time.Time) (int64, error) {
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	query := tx.Unscoped().Where("status <> ? AND created_at < ?", entities.NotificationStatusPending, before)
+
+	result := query.Delete(&entities.Notification{})
+	if result.Error != nil {
+		slog.Error("[DB] Notifications removing failed", "error", result.Error)
+
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, tx.Commit().Error
+}
+
+// GetDigestUsers returns users with digest delivery mode and users who still have queued digest items.
+func (r *Repository) GetDigestUsers(ctx context.Context) ([]entities.User, error) {
+	var users []entities.User
+
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	digestItems := tx.Session(&gorm.Session{NewDB: true}).
+		Table("digest_items").
+		Select("1").
+		Where("digest_items.user_id = users.id AND digest_items.deleted_at IS NULL")
+
+	query := tx.Where("delivery_mode <> ? OR EXISTS (?)", entities.DeliveryModeInstant, digestItems)
+	if err := query.Find(&users).Error; err != nil {
+		slog.Error("[DB] Get digest users failed", "error", err)
+
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (r *Repository) GetDigestItems(
+	ctx context.Context,
+	user *entities.User,
+) ([]entities.DigestItem, error) {
+	var digestItems []entities.DigestItem
+
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	if err := tx.Where("user_id = ?", user.ID).Order("id").Find(&digestItems).Error; err != nil {
+		slog.Error("[DB] Get digest items failed", "user", user.ID, "error", err)
+
+		return nil, err
+	}
+
+	return digestItems, nil
+}
+
+// EnqueueDigest moves digest items to the outbox as a single notification and marks the digest period as done.
+func (r *Repository) EnqueueDigest(
+	ctx context.Context,
+	user *entities.User,
+	digestItems []entities.DigestItem,
+	notifications []entities.Notification,
+	digestAt time.Time,
+) error {
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	if len(notifications) > 0 {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&notifications).Error; err != nil {
+			slog.Error("[DB] Digest notification creation failed", "user", user.ID, "error", err)
+
+			return err
+		}
+	}
+
+	if len(digestItems) > 0 {
+		if err := tx.Unscoped().Delete(&digestItems).Error; err != nil {
+			slog.Error("[DB] Digest items removing failed", "user", user.ID, "error", err)
+
+			return err
+		}
+	}
+
+	query := tx.Model(&entities.User{}).Where("id = ?", user.ID)
+	if err := query.Update("last_digest_at", digestAt).Error; err != nil {
+		slog.Error("[DB] User digest time update failed", "user", user.ID, "error", err)
+
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// UpdateUserSettings saves only the given user columns.
+func (r *Repository) UpdateUserSettings(
+	ctx context.Context,
+	user *entities.User,
+	columns ...string,
+) error {
+	tx := r.db.Begin().WithContext(ctx)
+
+	defer tx.Rollback()
+
+	if err := tx.Model(user).Select(columns).Updates(user).Error; err != nil {
+		slog.Error("[DB] User settings update failed", "user", user.ID, "error", err)
+
+		return err
+	}
+
+	return tx.Commit().Error
 }
@@ -7,12 +7,18 @@ import (
 	"log/slog"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 
 	"github.com/soltanoff/go_github_release_monitor_bot/internal/config"
 	"github.com/soltanoff/go_github_release_monitor_bot/internal/controller"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/importer"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/leader"
 	"github.com/soltanoff/go_github_release_monitor_bot/internal/monitor"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/monitor/github"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/notifier"
 	"github.com/soltanoff/go_github_release_monitor_bot/internal/repo"
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/templates"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -37,21 +43,39 @@ func Run() error {
 		return fmt.Errorf("[RUNNER]: %w", err)
 	}
 
-	bc, err := controller.NewBotController(cfg, repository)
+	githubClient := github.NewClient(cfg)
+	repositoryImporter := importer.NewImporter(repository, githubClient)
+
+	renderer, err := templates.NewRenderer(cfg.Template)
 	if err != nil {
 		return fmt.Errorf("[RUNNER]: %w", err)
 	}
 
-	g.Go(func() error {
-		bc.Start(ctx)
+	releaseMonitor := monitor.NewReleaseMonitor(cfg, repository, githubClient, repositoryImporter, renderer)
 
-		return nil
-	})
+	releaseLookup := monitor.NewReleaseLookup(githubClient, cfg.InlineCacheTTL)
+
+	bc, err := controller.NewBotController(cfg, repository, repositoryImporter, releaseMonitor, releaseLookup)
+	if err != nil {
+		return fmt.Errorf("[RUNNER]: %w", err)
+	}
+
+	dispatcher := notifier.NewDispatcher(cfg, bc, repository)
+	digester := notifier.NewDigester(repository)
+	elector := leader.NewElector(cfg, repository)
 
-	releaseMonitor := monitor.NewReleaseMonitor(cfg, bc, repository)
+	if cfg.WebhookURL != "" {
+		// every instance behind the load balancer serves commands
+		g.Go(func() error {
+			return bc.StartWebhook(ctx)
+		})
+	}
 
 	g.Go(func() error {
-		releaseMonitor.Start(ctx)
+		// only the leader polls GitHub and sends notifications, so replicas don't send duplicates
+		elector.Run(ctx, func(leaderCtx context.Context) {
+			runLeaderJobs(leaderCtx, cfg, bc, releaseMonitor, dispatcher, digester)
+		})
 
 		return nil
 	})
@@ -64,3 +88,24 @@ func Run() error {
 
 	return nil
 }
+
+func runLeaderJobs(
+	ctx context.Context,
+	cfg *config.Config,
+	bc *controller.BotController,
+	releaseMonitor *monitor.ReleaseMonitor,
+	dispatcher *notifier.Dispatcher,
+	digester *notifier.Digester,
+) {
+	var leaderJobs sync.WaitGroup
+
+	if cfg.WebhookURL == "" {
+		// concurrent long polls with one token fail with 409 Conflict, so the leader receives updates
+		leaderJobs.Go(func() { bc.Start(ctx) })
+	}
+
+	leaderJobs.Go(func() { releaseMonitor.Start(ctx) })
+	leaderJobs.Go(func() { dispatcher.Start(ctx) })
+	leaderJobs.Go(func() { digester.Start(ctx) })
+	leaderJobs.Wait()
+}
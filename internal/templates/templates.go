@@ -0,0 +1,345 @@
+package templates
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+	"unicode/utf8"
+
+	"github.com/soltanoff/go_github_release_monitor_bot/internal/entities"
+)
+
+const (
+	DefaultPreset string = "default"
+
+	// MaxTemplateLength bounds custom templates, so rendered messages fit the Telegram limit
+	MaxTemplateLength int = 1000
+
+	maxNotesLength int    = 1500
+	maxAssets      int    = 10
+	dateLayout     string = "2006-01-02"
+)
+
+var (
+	ErrTemplateTooLong     = errors.New("template is too long")
+	ErrTemplateInvalid     = errors.New("template is invalid")
+	ErrTemplateUnsupported = errors.New("template uses unsupported HTML")
+	ErrTemplateNested      = errors.New("template defines or calls other templates")
+	ErrTemplateLoop        = errors.New("template loops over anything but .Assets or nests loops")
+
+	errOutputTooLong = errors.New("output is too long")
+)
+
+// presets returns the built-in templates by name.
+func presets() map[string]string {
+	return map[string]string{
+		DefaultPreset: `<b>Release tag</b>: {{.URL}}`,
+		"short":       `<a href="{{.URL}}">{{.Repo}} {{.NewTag}}</a>`,
+		"detailed": `<b><a href="{{.RepoURL}}">{{.Repo}}</a></b>: ` +
+			`{{if .OldTag}}{{.OldTag}} → {{end}}<a href="{{.URL}}">{{.NewTag}}</a>` +
+			`{{if .Date}} ({{.Date}}){{end}}` +
+			`{{if .Notes}}` + "\n\n" + `<blockquote>{{.Notes}}</blockquote>{{end}}` +
+			`{{range .Assets}}` + "\n" + `• <a href="{{.URL}}">{{.Name}}</a>{{end}}`,
+	}
+}
+
+// allowedTags are the HTML tags supported by Telegram messages.
+func allowedTags() []string {
+	return []string{
+		"b", "strong", "i", "em", "u", "ins", "s", "strike", "del",
+		"a", "code", "pre", "blockquote", "tg-spoiler", "span",
+	}
+}
+
+// Preset returns the built-in template with the given name.
+func Preset(name string) (string, bool) {
+	text, ok := presets()[name]
+
+	return text, ok
+}
+
+// PresetNames returns the built-in template names in alphabetical order.
+func PresetNames() []string {
+	names := make([]string, 0, len(presets()))
+	for name := range presets() {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// Release describes a release for notification templates.
+type Release struct {
+	Repo        string
+	RepoURL     string
+	OldTag      string
+	NewTag      string
+	URL         string
+	Notes       string
+	PublishedAt time.Time
+	Assets      []Asset
+}
+
+type Asset struct {
+	Name string
+	URL  string
+}
+
+// releaseData is the set of variables available in templates, every value is HTML-escaped,
+// so it's safe in any place of the template.
+type releaseData struct {
+	Repo    string
+	RepoURL string
+	OldTag  string
+	NewTag  string
+	URL     string
+	Notes   string
+	Date    string
+	Assets  []Asset
+}
+
+// newReleaseData escapes release details for templates, long notes and asset lists are cut.
+func newReleaseData(release *Release) *releaseData {
+	data := releaseData{
+		Repo:    html.EscapeString(release.Repo),
+		RepoURL: html.EscapeString(release.RepoURL),
+		OldTag:  html.EscapeString(release.OldTag),
+		NewTag:  html.EscapeString(release.NewTag),
+		URL:     html.EscapeString(release.URL),
+		Notes:   html.EscapeString(truncate(strings.TrimSpace(release.Notes), maxNotesLength)),
+	}
+
+	if !release.PublishedAt.IsZero() {
+		data.Date = release.PublishedAt.Format(dateLayout)
+	}
+
+	for _, asset := range release.Assets[:min(len(release.Assets), maxAssets)] {
+		data.Assets = append(data.Assets, Asset{Name: html.EscapeString(asset.Name), URL: html.EscapeString(asset.URL)})
+	}
+
+	return &data
+}
+
+// SampleRelease is used to validate and preview templates.
+func SampleRelease() *Release {
+	return &Release{
+		Repo:        "owner/repo",
+		RepoURL:     "https://github.com/owner/repo",
+		OldTag:      "v1.0.0",
+		NewTag:      "v1.1.0",
+		URL:         "https://github.com/owner/repo/releases/tag/v1.1.0",
+		Notes:       "Bug fixes & improvements",
+		PublishedAt: time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC),
+		Assets: []Asset{{
+			Name: "repo_linux_amd64.tar.gz",
+			URL:  "https://github.com/owner/repo/releases/download/v1.1.0/repo_linux_amd64.tar.gz",
+		}},
+	}
+}
+
+// Renderer renders release notifications with the user template or the global one.
+type Renderer struct {
+	global *template.Template
+}
+
+// NewRenderer validates the global template, an empty one means the default preset.
+func NewRenderer(globalText string) (*Renderer, error) {
+	if globalText == "" {
+		globalText, _ = Preset(DefaultPreset)
+	}
+
+	global, err := Parse(globalText)
+	if err != nil {
+		return nil, fmt.Errorf("[TEMPLATES] invalid global notification template: %w", err)
+	}
+
+	return &Renderer{global: global}, nil
+}
+
+// Render falls back to the global template when the user template is empty or broken.
+func (r *Renderer) Render(userText string, release *Release) string {
+	data := newReleaseData(release)
+
+	if userText != "" {
+		userTemplate, err := Parse(userText)
+		if err == nil {
+			// the output is checked again, since conditions may produce different HTML for real data
+			if text, err := execute(userTemplate, data); err == nil && validateHTML(text) == nil {
+				return text
+			}
+		}
+	}
+
+	text, err := execute(r.global, data)
+	if err != nil {
+		// the global template is validated on start, so it's a fallback of the last resort
+		return fmt.Sprintf(`<b>Release tag</b>: %s`, data.URL)
+	}
+
+	return text
+}
+
+// Parse validates the template: it must be short, compile, execute with the documented variables
+// and produce well-formed HTML with the tags supported by Telegram only.
+func Parse(text string) (*template.Template, error) {
+	if utf8.RuneCountInString(text) > MaxTemplateLength {
+		return nil, ErrTemplateTooLong
+	}
+
+	parsed, err := template.New("notification").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTemplateInvalid, err)
+	}
+
+	// {{define}}, {{block}} and {{template}} would allow recursion, so templates are flat
+	if len(parsed.Templates()) > 1 || inspect(parsed.Root, 0, isTemplateCall) {
+		return nil, ErrTemplateNested
+	}
+
+	// a loop over a number may run for hours without any output, so templates loop only over
+	// the release lists, which are short, and loops aren't nested
+	if inspect(parsed.Root, 0, isCostlyLoop) {
+		return nil, ErrTemplateLoop
+	}
+
+	sample, err := execute(parsed, newReleaseData(SampleRelease()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTemplateInvalid, err)
+	}
+
+	if err := validateHTML(sample); err != nil {
+		return nil, err
+	}
+
+	return parsed, nil
+}
+
+// Preview validates the template and renders it with the sample release.
+func Preview(text string) (string, error) {
+	parsed, err := Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	return execute(parsed, newReleaseData(SampleRelease()))
+}
+
+// inspect tells whether the check matches any node of the tree, loops is the number of the enclosing {{range}}.
+func inspect(node parse.Node, loops int, check func(node parse.Node, loops int) bool) bool {
+	if check(node, loops) {
+		return true
+	}
+
+	switch node := node.(type) {
+	case *parse.ListNode:
+		return node != nil && slices.ContainsFunc(node.Nodes, func(child parse.Node) bool {
+			return inspect(child, loops, check)
+		})
+	case *parse.IfNode:
+		return inspect(node.List, loops, check) || inspect(node.ElseList, loops, check)
+	case *parse.RangeNode:
+		return inspect(node.List, loops+1, check) || inspect(node.ElseList, loops, check)
+	case *parse.WithNode:
+		return inspect(node.List, loops, check) || inspect(node.ElseList, loops, check)
+	default:
+		return false
+	}
+}
+
+func isTemplateCall(node parse.Node, _ int) bool {
+	_, ok := node.(*parse.TemplateNode)
+
+	return ok
+}
+
+// isCostlyLoop allows {{range .Assets}} and {{range $.Assets}} only, at the top level.
+func isCostlyLoop(node parse.Node, loops int) bool {
+	rangeNode, ok := node.(*parse.RangeNode)
+	if !ok {
+		return false
+	}
+
+	if loops > 0 || len(rangeNode.Pipe.Cmds) != 1 || len(rangeNode.Pipe.Cmds[0].Args) != 1 {
+		return true
+	}
+
+	switch arg := rangeNode.Pipe.Cmds[0].Args[0].(type) {
+	case *parse.FieldNode:
+		return false
+	case *parse.VariableNode:
+		return len(arg.Ident) < 2 || arg.Ident[0] != "$" //nolint:mnd // the root variable and a field
+	default:
+		return true
+	}
+}
+
+// execute renders the template, the output is cut to the Telegram message limit.
+func execute(parsed *template.Template, data *releaseData) (string, error) {
+	text := cappedWriter{limit: entities.MaxMessageLength}
+
+	if err := parsed.Execute(&text, data); err != nil && !errors.Is(err, errOutputTooLong) {
+		return "", fmt.Errorf("[TEMPLATES] template execution failed: %w", err)
+	}
+
+	return text.String(), nil
+}
+
+// cappedWriter keeps up to limit bytes cut at a character boundary, then it stops the execution.
+type cappedWriter struct {
+	strings.Builder
+
+	limit int
+}
+
+func (w *cappedWriter) Write(p []byte) (int, error) {
+	left := w.limit - w.Len()
+	if len(p) <= left {
+		return w.Builder.Write(p)
+	}
+
+	for left > 0 && !utf8.RuneStart(p[left]) {
+		left--
+	}
+
+	w.Builder.Write(p[:left])
+
+	return left, errOutputTooLong
+}
+
+func validateHTML(text string) error {
+	// the text is wrapped into a root element, so the XML decoder checks it's well-formed
+	decoder := xml.NewDecoder(strings.NewReader("<message>" + text + "</message>"))
+
+	for root := true; ; root = false {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrTemplateUnsupported, err)
+		}
+
+		element, ok := token.(xml.StartElement)
+		if ok && !root && !slices.Contains(allowedTags(), element.Name.Local) {
+			return fmt.Errorf("%w: <%s>", ErrTemplateUnsupported, element.Name.Local)
+		}
+	}
+}
+
+func truncate(text string, maxLength int) string {
+	if utf8.RuneCountInString(text) <= maxLength {
+		return text
+	}
+
+	return string([]rune(text)[:maxLength]) + "…"
+}